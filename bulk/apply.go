@@ -0,0 +1,179 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+const (
+	// Number of rows applied at once, unless changed with Options.Concurrency
+	DefaultConcurrency int = 4
+)
+
+// Options configures Import()
+type Options struct {
+	MatchKey    MatchKey
+	DryRun      bool // plan the import and report on it, without changing anything
+	Concurrency int  // rows applied at once, 0 uses DefaultConcurrency
+}
+
+// Report lists the result of every row of an import
+type Report struct {
+	DryRun  bool
+	Results []Result
+}
+
+// Summary counts the rows of a report by outcome
+type Summary struct {
+	Rows      int  `json:"rows"`
+	Created   int  `json:"created"`
+	Updated   int  `json:"updated"`
+	Unchanged int  `json:"unchanged"`
+	Failed    int  `json:"failed"`
+	Skipped   int  `json:"skipped"`
+	DryRun    bool `json:"dryRun"`
+}
+
+//
+// Import reads the existing users from JumpCloud and plans the import of source. Unless
+// opts.DryRun is set, it then applies the plan. The report lists every row of source,
+// in order, whether the import was applied or not.
+//
+func Import(ctx context.Context, jc jcapi.JCAPI, source Source, opts Options) (*Report, error) {
+	existing, err := jc.GetSystemUsersContext(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not read system users, err='%w'", err)
+	}
+
+	plan, err := NewPlan(source, existing, opts.MatchKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return plan.DryRun(), nil
+	}
+
+	return plan.Apply(ctx, jc, opts.Concurrency), nil
+}
+
+// DryRun returns the report of the plan, without applying it.
+func (plan *Plan) DryRun() *Report {
+	report := &Report{DryRun: true, Results: make([]Result, len(plan.Results))}
+	copy(report.Results, plan.Results)
+
+	return report
+}
+
+//
+// Apply creates and updates the users of the plan, up to concurrency rows at a time
+// (DefaultConcurrency when 0). A row that fails doesn't stop the others; once ctx is
+// done, the rows not yet started are skipped.
+//
+func (plan *Plan) Apply(ctx context.Context, jc jcapi.JCAPI, concurrency int) *Report {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	report := plan.DryRun()
+	report.DryRun = false
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				applyResult(ctx, jc, &report.Results[idx])
+			}
+		}()
+	}
+
+	next := 0
+
+dispatch:
+	for ; next < len(report.Results) && ctx.Err() == nil; next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	for ; next < len(report.Results); next++ {
+		report.Results[next].Status = StatusSkipped
+		report.Results[next].Error = ctx.Err().Error()
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return report
+}
+
+func applyResult(ctx context.Context, jc jcapi.JCAPI, result *Result) {
+	var err error
+
+	switch result.Action {
+	case ActionCreate:
+		result.UserId, err = jc.AddUpdateUserContext(ctx, jcapi.Insert, result.user)
+	case ActionUpdate:
+		_, err = jc.UpdateUserFieldsContext(ctx, result.UserId, result.changes)
+	case ActionError:
+		result.Status = StatusFailed
+		return
+	}
+
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return
+	}
+
+	result.Status = StatusDone
+}
+
+// Summary counts the rows of the report by outcome.
+func (report *Report) Summary() Summary {
+	summary := Summary{Rows: len(report.Results), DryRun: report.DryRun}
+
+	for _, result := range report.Results {
+		switch {
+		case result.Status == StatusFailed || result.Action == ActionError:
+			summary.Failed++
+		case result.Status == StatusSkipped:
+			summary.Skipped++
+		case result.Action == ActionCreate:
+			summary.Created++
+		case result.Action == ActionUpdate:
+			summary.Updated++
+		default:
+			summary.Unchanged++
+		}
+	}
+
+	return summary
+}
+
+// WriteJSON writes the result of every row of the report to w as JSON, one per line.
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	for _, result := range report.Results {
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("ERROR: Could not write report of row %d, err='%w'", result.Row, err)
+		}
+	}
+
+	return nil
+}
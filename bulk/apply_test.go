@@ -0,0 +1,181 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+//
+// userServer lists its users on /systemusers, creates users POSTed there and updates
+// the ones PUT on /systemusers/<id>, failing any write to a user in failIds.
+//
+type userServer struct {
+	sync.Mutex
+
+	users   []map[string]interface{}
+	failIds map[string]bool
+	writes  []string
+}
+
+func (s *userServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	var fields map[string]interface{}
+	json.Unmarshal(body, &fields)
+
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/systemusers"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalCount": len(s.users), "results": s.users})
+		return
+	case r.Method == http.MethodPost && id == "":
+		s.writes = append(s.writes, "POST "+fields["username"].(string))
+		fields["_id"] = "new-" + fields["username"].(string)
+		json.NewEncoder(w).Encode(fields)
+		return
+	case r.Method == http.MethodPut:
+		s.writes = append(s.writes, "PUT "+id+" "+string(body))
+		if s.failIds[id] {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for _, user := range s.users {
+			if user["_id"] == id {
+				for name, value := range fields {
+					user[name] = value
+				}
+				json.NewEncoder(w).Encode(user)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func newUserServer(t *testing.T, failIds ...string) (*userServer, jcapi.JCAPI) {
+	server := &userServer{
+		users: []map[string]interface{}{
+			{"_id": "1", "username": "jdoe", "email": "jdoe@example.com", "firstname": "John", "external_dn": "", "external_source_type": ""},
+			{"_id": "2", "username": "asmith", "email": "asmith@example.com", "firstname": "Anna", "external_dn": "", "external_source_type": ""},
+		},
+		failIds: make(map[string]bool),
+	}
+
+	for _, id := range failIds {
+		server.failIds[id] = true
+	}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, jcapi.NewJCAPI("key", ts.URL)
+}
+
+func testSource() Source {
+	return Records(
+		Record{User: jcapi.JCUser{UserName: "jdoe", FirstName: "Johnny"}, Fields: []string{"username", "firstname"}},
+		Record{User: jcapi.JCUser{UserName: "asmith", FirstName: "Ann"}, Fields: []string{"username", "firstname"}},
+		Record{User: jcapi.JCUser{UserName: "new", Email: "new@example.com"}, Fields: []string{"username", "email"}},
+		Record{User: jcapi.JCUser{UserName: "jdoe", LastName: "Doe"}, Fields: []string{"lastname"}},
+	)
+}
+
+func TestImport(t *testing.T) {
+	server, jc := newUserServer(t, "2")
+
+	report, err := Import(context.Background(), jc, testSource(), Options{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Import() failed, err='%s'", err)
+	}
+
+	var statuses []string
+	for _, result := range report.Results {
+		statuses = append(statuses, string(result.Action)+":"+string(result.Status))
+	}
+
+	if s := strings.Join(statuses, " "); s != "update:done update:failed create:done error:failed" {
+		t.Fatalf("Unexpected results: %s", s)
+	}
+
+	if report.Results[2].UserId != "new-new" || report.Results[1].Error == "" {
+		t.Fatalf("Unexpected results: %+v", report.Results)
+	}
+
+	sort.Strings(server.writes)
+	if s := strings.Join(server.writes, "\n"); s != "POST new\nPUT 1 {\"firstname\":\"Johnny\"}\nPUT 2 {\"firstname\":\"Ann\"}" {
+		t.Fatalf("Unexpected writes:\n%s", s)
+	}
+
+	summary := report.Summary()
+	if summary != (Summary{Rows: 4, Created: 1, Updated: 1, Failed: 2}) {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+
+	var buffer bytes.Buffer
+	if err := report.WriteJSON(&buffer); err != nil {
+		t.Fatalf("WriteJSON() failed, err='%s'", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], `{"row":1,"key":"jdoe","action":"update","status":"done","userId":"1","changes":[{"field":"firstname","from":"John","to":"Johnny"}]}`) {
+		t.Fatalf("Unexpected report:\n%s", buffer.String())
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	server, jc := newUserServer(t)
+
+	report, err := Import(context.Background(), jc, testSource(), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import() failed, err='%s'", err)
+	}
+
+	if len(server.writes) != 0 {
+		t.Fatalf("A dry run wrote %v", server.writes)
+	}
+
+	summary := report.Summary()
+	if summary != (Summary{Rows: 4, Created: 1, Updated: 2, Failed: 1, DryRun: true}) {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+
+	for _, result := range report.Results {
+		if result.Status != StatusPlanned {
+			t.Fatalf("Unexpected status in a dry run: %+v", result)
+		}
+	}
+}
+
+func TestApplyCancelled(t *testing.T) {
+	server, jc := newUserServer(t)
+
+	plan, err := NewPlan(testSource(), nil, MatchUserName)
+	if err != nil {
+		t.Fatalf("NewPlan() failed, err='%s'", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := plan.Apply(ctx, jc, 1)
+
+	if summary := report.Summary(); summary.Skipped+summary.Failed != summary.Rows || len(server.writes) != 0 {
+		t.Fatalf("Expected every row to be skipped or failed, got %+v and writes %v", summary, server.writes)
+	}
+}
@@ -0,0 +1,294 @@
+//
+// Package bulk imports system users into JumpCloud in bulk: it matches a stream of
+// desired users with the existing ones, plans the users to create and the fields to
+// update, and applies the plan with bounded concurrency, reporting on every row.
+//
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+// MatchKey is the field a desired user is matched with an existing user on
+type MatchKey int
+
+const (
+	MatchUserName MatchKey = iota
+	MatchEmail
+	MatchExternalDN
+)
+
+func (key MatchKey) String() string {
+	switch key {
+	case MatchUserName:
+		return "username"
+	case MatchEmail:
+		return "email"
+	case MatchExternalDN:
+		return "external_dn"
+	}
+
+	return fmt.Sprintf("MatchKey(%d)", int(key))
+}
+
+// value returns the key of the user, lowercased as keys are compared regardless of case
+func (key MatchKey) value(user jcapi.JCUser) string {
+	switch key {
+	case MatchEmail:
+		return strings.ToLower(user.Email)
+	case MatchExternalDN:
+		return strings.ToLower(user.ExternalDN)
+	}
+
+	return strings.ToLower(user.UserName)
+}
+
+// Action is what a plan does for a row
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionNone   Action = "none"  // the user is already as desired
+	ActionError  Action = "error" // the row can't be imported, see Result.Error
+)
+
+// Status is how far a row of a plan got
+type Status string
+
+const (
+	StatusPlanned Status = "planned" // not applied, as in a dry run
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped" // not applied, as the import was cancelled
+)
+
+//
+// Record is a desired user. Only the fields of User named in Fields (by their JSON name,
+// as in jcapi.JCUserChanges) are applied to an existing user or, when Fields is nil,
+// those that are not empty in User. The attributes of User are merged into those of
+// the existing user, rather than replacing them.
+//
+type Record struct {
+	User   jcapi.JCUser
+	Fields []string
+}
+
+//
+// Source is a stream of desired users. Next returns io.EOF once all of them have been
+// read, a *RowError for a record it can't read, which only fails its own row, and any
+// other error, such as a failed read, to abort the import.
+//
+type Source interface {
+	Next() (Record, error)
+}
+
+//
+// RowError is the error of a record a source can't read, such as a CSV row with too
+// many cells. Record holds what could be read of it, for the key of its row.
+//
+type RowError struct {
+	Record Record
+	Err    error
+}
+
+func (err *RowError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *RowError) Unwrap() error {
+	return err.Err
+}
+
+// FieldChange is the change of one field of a user
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+//
+// Result is a row of a plan, and then of its report: what is done for one desired user,
+// and how it went.
+//
+type Result struct {
+	Row     int           `json:"row"` // the position of the record in its source, from 1
+	Key     string        `json:"key"`
+	Action  Action        `json:"action"`
+	Status  Status        `json:"status"`
+	UserId  string        `json:"userId,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+	Error   string        `json:"error,omitempty"`
+
+	user    jcapi.JCUser        // the user to create
+	changes jcapi.JCUserChanges // the fields to update
+}
+
+// Plan lists the result of every row of a source, before it is applied
+type Plan struct {
+	MatchKey MatchKey
+	Results  []Result
+}
+
+// The value of a password in FieldChange, which never shows it
+const maskedPassword string = "********"
+
+//
+// NewPlan reads all the records of source, and matches them with existing on key to plan
+// what to do with each one. Records the source can't read, two records for the same
+// key, records without a key and records matching several existing users are errors of
+// their own row, not of the plan.
+//
+func NewPlan(source Source, existing []jcapi.JCUser, key MatchKey) (*Plan, error) {
+	byKey := make(map[string][]jcapi.JCUser)
+
+	for _, user := range existing {
+		if value := key.value(user); value != "" {
+			byKey[value] = append(byKey[value], user)
+		}
+	}
+
+	plan := &Plan{MatchKey: key}
+	seen := make(map[string]int)
+
+	for row := 1; ; row++ {
+		record, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			result := Result{Row: row, Key: key.value(rowErr.Record.User), Action: ActionError, Status: StatusPlanned, Error: rowErr.Error()}
+			plan.Results = append(plan.Results, result)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Could not read row %d, err='%w'", row, err)
+		}
+
+		result := Result{Row: row, Key: key.value(record.User), Status: StatusPlanned}
+		matches := byKey[result.Key]
+
+		fieldErr := checkFields(record)
+
+		switch {
+		case fieldErr != nil:
+			result.Action, result.Error = ActionError, fieldErr.Error()
+		case result.Key == "":
+			result.Action, result.Error = ActionError, fmt.Sprintf("no %s to match users on", key)
+		case seen[result.Key] != 0:
+			result.Action, result.Error = ActionError, fmt.Sprintf("%s '%s' already imported by row %d", key, result.Key, seen[result.Key])
+		case len(matches) > 1:
+			result.Action, result.Error = ActionError, fmt.Sprintf("%s '%s' matches %d users", key, result.Key, len(matches))
+		case len(matches) == 0:
+			planCreate(&result, record)
+		default:
+			planUpdate(&result, record, matches[0])
+		}
+
+		if result.Key != "" && seen[result.Key] == 0 {
+			seen[result.Key] = row
+		}
+
+		plan.Results = append(plan.Results, result)
+	}
+
+	return plan, nil
+}
+
+// checkFields checks that the record only names fields of a user
+func checkFields(record Record) error {
+	_, err := jcapi.NewUserChanges(record.User, record.Fields...)
+	return err
+}
+
+func planCreate(result *Result, record Record) {
+	result.Action = ActionCreate
+	result.user = record.User
+	result.user.Id = ""
+
+	changes := jcapi.DiffUsers(jcapi.JCUser{}, result.user)
+	if record.Fields != nil {
+		changes = selectFields(changes, record.Fields)
+	}
+
+	result.Changes = fieldChanges(nil, changes)
+}
+
+func planUpdate(result *Result, record Record, existing jcapi.JCUser) {
+	result.UserId = existing.Id
+
+	desired := record.User
+	desired.Id = existing.Id
+	desired.Attributes = jcapi.MergeAttributes(existing.Attributes, jcapi.AttributeMerge{Set: record.User.Attributes})
+
+	// Without a list of fields, the fields left empty in the record are not part of it:
+	// they would otherwise clear the data of the existing user
+	fields := record.Fields
+	if fields == nil {
+		fields = jcapi.DiffUsers(jcapi.JCUser{}, record.User).Fields()
+	}
+
+	changes := selectFields(jcapi.DiffUsers(existing, desired), fields)
+
+	result.Action = ActionNone
+
+	if len(changes) > 0 {
+		result.Action = ActionUpdate
+		result.changes = changes
+		result.Changes = fieldChanges(&existing, changes)
+	}
+}
+
+func selectFields(changes jcapi.JCUserChanges, fields []string) jcapi.JCUserChanges {
+	selected := make(jcapi.JCUserChanges)
+
+	for _, field := range fields {
+		if value, ok := changes[field]; ok {
+			selected[field] = value
+		}
+	}
+
+	return selected
+}
+
+// fieldChanges lists changes in the order of the names of their fields, from nil for a new user
+func fieldChanges(from *jcapi.JCUser, changes jcapi.JCUserChanges) []FieldChange {
+	var list []FieldChange
+
+	for _, field := range changes.Fields() {
+		change := FieldChange{Field: field, To: changes[field]}
+
+		if from != nil {
+			if current, err := jcapi.NewUserChanges(*from, field); err == nil {
+				change.From = current[field]
+			}
+		}
+
+		if field == "password" {
+			change.From, change.To = nil, maskedPassword
+		}
+
+		list = append(list, change)
+	}
+
+	return list
+}
+
+// Count returns the number of rows of the plan for each action.
+func (plan *Plan) Count() map[Action]int {
+	counts := make(map[Action]int)
+
+	for _, result := range plan.Results {
+		counts[result.Action]++
+	}
+
+	return counts
+}
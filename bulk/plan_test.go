@@ -0,0 +1,213 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+func changesString(changes []FieldChange) string {
+	var list []string
+
+	for _, change := range changes {
+		list = append(list, fmt.Sprintf("%s:%v>%v", change.Field, change.From, change.To))
+	}
+
+	return strings.Join(list, " ")
+}
+
+func TestNewPlan(t *testing.T) {
+	existing := []jcapi.JCUser{
+		{Id: "1", UserName: "jdoe", Email: "jdoe@example.com", FirstName: "John", Attributes: []jcapi.JCUserAttribute{{Name: "team", Value: "ops"}}},
+		{Id: "2", UserName: "asmith", Email: "asmith@example.com", FirstName: "Anna"},
+		{Id: "3", UserName: "dup1", Email: "dup@example.com"},
+		{Id: "4", UserName: "dup2", Email: "DUP@example.com"},
+	}
+
+	source := Records(
+		Record{User: jcapi.JCUser{Email: "JDOE@example.com", FirstName: "Johnny", Password: "s3cret"}, Fields: []string{"firstname", "password"}},
+		Record{User: jcapi.JCUser{Email: "asmith@example.com", FirstName: "Anna"}, Fields: []string{"firstname"}},
+		Record{User: jcapi.JCUser{UserName: "new", Email: "new@example.com", Sudo: true}, Fields: []string{"username", "email", "sudo"}},
+		Record{User: jcapi.JCUser{Email: "dup@example.com"}, Fields: []string{}},
+		Record{User: jcapi.JCUser{FirstName: "Nobody"}, Fields: []string{"firstname"}},
+		Record{User: jcapi.JCUser{Email: "jdoe@example.com"}, Fields: []string{}},
+		Record{User: jcapi.JCUser{Email: "bad@example.com"}, Fields: []string{"shoe_size"}},
+		Record{User: jcapi.JCUser{Email: "asmith@example.com", Attributes: []jcapi.JCUserAttribute{{Name: "team", Value: "dev"}}}, Fields: []string{"attributes"}},
+	)
+
+	plan, err := NewPlan(source, existing, MatchEmail)
+	if err != nil {
+		t.Fatalf("NewPlan() failed, err='%s'", err)
+	}
+
+	expected := []struct {
+		action  Action
+		userId  string
+		changes string
+	}{
+		{ActionUpdate, "1", "firstname:John>Johnny password:<nil>>********"},
+		{ActionNone, "2", ""},
+		{ActionCreate, "", "email:<nil>>new@example.com sudo:<nil>>true username:<nil>>new"},
+		{ActionError, "", ""},
+		{ActionError, "", ""},
+		{ActionError, "", ""},
+		{ActionError, "", ""},
+		{ActionError, "", ""},
+	}
+
+	if len(plan.Results) != len(expected) {
+		t.Fatalf("Expected %d rows, got %d", len(expected), len(plan.Results))
+	}
+
+	for idx, result := range plan.Results {
+		want := expected[idx]
+
+		if result.Row != idx+1 || result.Action != want.action || result.UserId != want.userId || result.Status != StatusPlanned {
+			t.Errorf("Unexpected row %d: %+v", idx+1, result)
+		}
+
+		if s := changesString(result.Changes); s != want.changes {
+			t.Errorf("Unexpected changes for row %d: got '%s', want '%s'", idx+1, s, want.changes)
+		}
+
+		if (want.action == ActionError) != (result.Error != "") {
+			t.Errorf("Unexpected error for row %d: '%s'", idx+1, result.Error)
+		}
+	}
+
+	// A second record for the same user is an error, the first one stands
+	if !strings.Contains(plan.Results[7].Error, "row 2") {
+		t.Errorf("Expected row 8 to clash with row 2, got '%s'", plan.Results[7].Error)
+	}
+
+	counts := plan.Count()
+	if counts[ActionCreate] != 1 || counts[ActionUpdate] != 1 || counts[ActionNone] != 1 || counts[ActionError] != 5 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+}
+
+func TestNewPlanMergesAttributes(t *testing.T) {
+	existing := []jcapi.JCUser{
+		{Id: "1", UserName: "jdoe", Attributes: []jcapi.JCUserAttribute{{Name: "team", Value: "ops"}, {Name: "site", Value: "nyc"}}},
+	}
+
+	source := Records(Record{
+		User:   jcapi.JCUser{UserName: "JDoe", Attributes: []jcapi.JCUserAttribute{{Name: "team", Value: "dev"}}},
+		Fields: []string{"attributes"},
+	})
+
+	plan, err := NewPlan(source, existing, MatchUserName)
+	if err != nil {
+		t.Fatalf("NewPlan() failed, err='%s'", err)
+	}
+
+	result := plan.Results[0]
+
+	if result.Action != ActionUpdate || fmt.Sprint(result.changes["attributes"]) != "[{team dev} {site nyc}]" {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+}
+
+func TestNewPlanWithoutFieldsKeepsEmptyFields(t *testing.T) {
+	existing := []jcapi.JCUser{{Id: "1", UserName: "jdoe", Email: "jdoe@example.com", FirstName: "John", Sudo: true}}
+
+	plan, err := NewPlan(Records(Record{User: jcapi.JCUser{UserName: "jdoe", Email: "john@example.com"}}), existing, MatchUserName)
+	if err != nil {
+		t.Fatalf("NewPlan() failed, err='%s'", err)
+	}
+
+	result := plan.Results[0]
+
+	if result.Action != ActionUpdate || changesString(result.Changes) != "email:jdoe@example.com>john@example.com" {
+		t.Fatalf("Only the email should change, got %+v", result)
+	}
+
+	if _, ok := result.changes["firstname"]; ok || len(result.changes) != 1 {
+		t.Fatalf("Unexpected changes sent: %v", result.changes)
+	}
+}
+
+func TestCSVSource(t *testing.T) {
+	source, err := NewCSVSource(strings.NewReader("username, email, sudo, attributes.costCenter\n" +
+		"jdoe,jdoe@example.com,yes,42\n" +
+		"asmith,,false,\n"))
+	if err != nil {
+		t.Fatalf("NewCSVSource() failed, err='%s'", err)
+	}
+
+	first, err := source.Next()
+	if err != nil {
+		t.Fatalf("Next() failed, err='%s'", err)
+	}
+
+	if first.User.UserName != "jdoe" || first.User.Email != "jdoe@example.com" || !first.User.Sudo ||
+		fmt.Sprint(first.User.Attributes) != "[{costCenter 42}]" || strings.Join(first.Fields, " ") != "username email sudo attributes" {
+		t.Fatalf("Unexpected record: %+v", first)
+	}
+
+	second, err := source.Next()
+	if err != nil || second.User.UserName != "asmith" || strings.Join(second.Fields, " ") != "username sudo" {
+		t.Fatalf("Unexpected record %+v, err='%v'", second, err)
+	}
+
+	if _, err := source.Next(); err == nil {
+		t.Fatalf("Expected io.EOF after the last row")
+	}
+
+	for _, header := range []string{"username,shoe_size", "username,tags", "username,attributes.cost center", "username,_id"} {
+		if _, err := NewCSVSource(strings.NewReader(header + "\n")); err == nil {
+			t.Errorf("Expected an error for header '%s'", header)
+		}
+	}
+
+	source, _ = NewCSVSource(strings.NewReader("username,sudo\njdoe,maybe\n"))
+
+	var rowErr *RowError
+	if _, err := source.Next(); !errors.As(err, &rowErr) || rowErr.Record.User.UserName != "jdoe" {
+		t.Errorf("Expected a row error for a bad boolean, got '%v'", err)
+	}
+}
+
+func TestNewPlanReportsBadRows(t *testing.T) {
+	source, err := NewCSVSource(strings.NewReader("username,sudo\n" +
+		"jdoe,yes\n" +
+		"asmith,maybe\n" +
+		"bwayne\n" +
+		"ckent,no,extra\n" +
+		"dprince,\"no\n" +
+		"eprince,no\n"))
+	if err != nil {
+		t.Fatalf("NewCSVSource() failed, err='%s'", err)
+	}
+
+	plan, err := NewPlan(source, nil, MatchUserName)
+	if err != nil {
+		t.Fatalf("NewPlan() failed, err='%s'", err)
+	}
+
+	var rows []string
+	for _, result := range plan.Results {
+		rows = append(rows, fmt.Sprintf("%d:%s:%s", result.Row, result.Key, result.Action))
+	}
+
+	// The quote left open takes the rest of the file with it
+	if s := strings.Join(rows, " "); s != "1:jdoe:create 2:asmith:error 3::error 4::error 5::error" {
+		t.Fatalf("Unexpected rows: %s", s)
+	}
+
+	if !strings.Contains(plan.Results[1].Error, "'sudo' is not a boolean") || !strings.Contains(plan.Results[2].Error, "1 cells, the header 2") {
+		t.Fatalf("Unexpected errors: %+v", plan.Results)
+	}
+
+	// Failing to read the source still aborts the plan
+	source, _ = NewCSVSource(io.MultiReader(strings.NewReader("username\njdoe\n"), iotest.ErrReader(errors.New("disk failure"))))
+
+	if _, err := NewPlan(source, nil, MatchUserName); err == nil || !strings.Contains(err.Error(), "disk failure") {
+		t.Fatalf("Expected the read error, got '%v'", err)
+	}
+}
@@ -0,0 +1,213 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+// The prefix of the CSV columns holding user attributes, as in "attributes.costCenter"
+const attributeColumnPrefix string = "attributes."
+
+type recordSource struct {
+	records []Record
+}
+
+func (s *recordSource) Next() (Record, error) {
+	if len(s.records) == 0 {
+		return Record{}, io.EOF
+	}
+
+	record := s.records[0]
+	s.records = s.records[1:]
+
+	return record, nil
+}
+
+// Records returns a source that reads the given records, in order.
+func Records(records ...Record) Source {
+	return &recordSource{records: records}
+}
+
+// Users returns a source that reads the given users, the fields they set desired.
+func Users(users []jcapi.JCUser) Source {
+	records := make([]Record, len(users))
+
+	for idx, user := range users {
+		records[idx] = Record{User: user}
+	}
+
+	return Records(records...)
+}
+
+type csvSource struct {
+	reader  *csv.Reader
+	columns []csvColumn
+}
+
+// csvColumn sets either a field of JCUser, or an attribute
+type csvColumn struct {
+	name      string // the JSON name of the field, or of the attribute column
+	field     int
+	attribute string
+}
+
+//
+// NewCSVSource returns a source that reads users from r in CSV. The header names the
+// JSON fields of jcapi.JCUser each column sets (e.g. "username", "email" or "sudo"),
+// and "attributes.<name>" the user attributes. Only the fields set in a row are
+// desired, empty cells are left alone. A row that can't be parsed, that doesn't have a
+// cell for each column or that has a bad boolean is a *RowError.
+//
+func NewCSVSource(r io.Reader) (Source, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // checked by Next(), to fail only the row
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not read CSV header, err='%w'", err)
+	}
+
+	fields := csvFields()
+	source := &csvSource{reader: reader}
+
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+
+		if strings.HasPrefix(name, attributeColumnPrefix) {
+			attribute := strings.TrimPrefix(name, attributeColumnPrefix)
+			if err := jcapi.ValidateAttributeName(attribute); err != nil {
+				return nil, err
+			}
+
+			source.columns = append(source.columns, csvColumn{name: name, attribute: attribute})
+			continue
+		}
+
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("ERROR: CSV column '%s' is not a string or bool field of a user", name)
+		}
+
+		source.columns = append(source.columns, csvColumn{name: name, field: field})
+	}
+
+	return source, nil
+}
+
+// csvFields maps the JSON names of the string and bool fields of JCUser to their index
+func csvFields() map[string]int {
+	fields := make(map[string]int)
+	userType := reflect.TypeOf(jcapi.JCUser{})
+
+	for idx := 0; idx < userType.NumField(); idx++ {
+		field := userType.Field(idx)
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "_id" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool:
+			fields[name] = idx
+		}
+	}
+
+	return fields
+}
+
+func (s *csvSource) Next() (Record, error) {
+	row, err := s.reader.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{}, &RowError{Err: fmt.Errorf("ERROR: Could not parse CSV row, err='%w'", err)}
+	}
+
+	if err != nil {
+		return Record{}, err
+	}
+
+	if len(row) != len(s.columns) {
+		return Record{}, &RowError{Err: fmt.Errorf("ERROR: CSV row has %d cells, the header %d columns", len(row), len(s.columns))}
+	}
+
+	var record Record
+	var rowErr *RowError
+
+	user := reflect.ValueOf(&record.User).Elem()
+
+	for idx, column := range s.columns {
+		value := strings.TrimSpace(row[idx])
+		if value == "" {
+			continue
+		}
+
+		if column.attribute != "" {
+			record.User.Attributes = append(record.User.Attributes, jcapi.JCUserAttribute{Name: column.attribute, Value: value})
+			if !containsString(record.Fields, "attributes") {
+				record.Fields = append(record.Fields, "attributes")
+			}
+			continue
+		}
+
+		field := user.Field(column.field)
+
+		if field.Kind() == reflect.Bool {
+			b, err := parseBool(value)
+			if err != nil {
+				// Read the rest of the row anyway, for the key of the row in the report
+				if rowErr == nil {
+					rowErr = &RowError{Err: fmt.Errorf("ERROR: Column '%s' is not a boolean: '%s'", column.name, value)}
+				}
+				continue
+			}
+
+			field.SetBool(b)
+		} else {
+			field.SetString(value)
+		}
+
+		record.Fields = append(record.Fields, column.name)
+	}
+
+	if record.Fields == nil {
+		record.Fields = []string{}
+	}
+
+	if rowErr != nil {
+		rowErr.Record = record
+		return Record{}, rowErr
+	}
+
+	return record, nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/TheJumpCloud/jcapi"
+	"github.com/TheJumpCloud/jcapi/bulk"
 )
 
 //
@@ -35,7 +38,8 @@ import (
 //     the user to provide an appropriate password for their account.
 //
 // 3 - The value of the SUDO_FLAG will always be applied to this user.  All
-//     other optional values specified will be applied as appropriate.
+//     other optional values specified will be applied as appropriate, while
+//     those left empty are left as they are on an existing user.
 //
 // 4 - When the host_name is specified, a tag will be created or updated for
 //     this USER_NAME associated with the host_name provided.
@@ -43,10 +47,20 @@ import (
 //     specifying these values here will result in the account on host_name
 //     being "taken over" by JumpCloud)
 //
-// 5 - If a line in the CSV file cannot be processed, the error will be
-//     reported to stderr, and processing will continue.
+// 5 - Passwords are checked against jcapi.DefaultPasswordPolicy(), and against
+//     the banned passwords listed (one per line) in the file given with -banned,
+//     before being sent. A line with a weak password is reported with every rule
+//     its password breaks, and nothing is sent for it.
 //
-// 6 - A summary is printed at the conclusion of processing.
+// 6 - If a line in the CSV file cannot be processed, the error will be
+//     reported, and processing will continue.
+//
+// 7 - A summary is printed at the conclusion of processing. With -dry-run,
+//     the lines are only checked and matched with the existing users, and
+//     nothing is changed in JumpCloud.
+//
+// The users are planned and applied with the bulk package, a few at a time,
+// and the tags are created once all of them are in JumpCloud.
 //
 // Because the program performs updates on existing values, and inserts
 // otherwise, any given CSV file can be run against JumpCloud multiple
@@ -76,177 +90,290 @@ func GetUserIdFromUserName(users []jcapi.JCUser, name string) string {
 }
 
 //
-// Process the line read from the CSV file into JumpCloud. (helper function)
+// A line of the CSV file: the user to import, and the host and administrators
+// of the tag to create for it. (helper type)
+//
+
+type csvLine struct {
+	record bulk.Record
+	host   string
+	admins []string
+	err    error // the line cannot be imported
+}
+
+//
+// Parse the line read from the CSV file into the user to import, with only the
+// fields the line sets. (helper function)
 //
 
-func ProcessCSVRecord(jc jcapi.JCAPI, userList *[]jcapi.JCUser, csvRecord []string) (err error) {
+func parseCSVRecord(csvRecord []string) (line csvLine) {
 	// Verify the record is complete enough to process
 	if len(csvRecord) < 9 {
-		err = fmt.Errorf("Line is improperly formatted (missing fields), skipping line.")
+		line.err = fmt.Errorf("Line is improperly formatted (missing fields), skipping line.")
 		return
 	}
 
-	// Setup work variables
-	var currentUser jcapi.JCUser
-	var currentHost string
-
-	currentAdmins := make(map[string]string) // "user name", "user id"
+	user := &line.record.User
 
 	var fieldMap = map[int]*string{
-		0: &currentUser.FirstName,
-		1: &currentUser.LastName,
-		2: &currentUser.UserName,
-		3: &currentUser.Email,
-		4: &currentUser.Uid,
-		5: &currentUser.Gid,
+		0: &user.FirstName,
+		1: &user.LastName,
+		2: &user.UserName,
+		3: &user.Email,
+		4: &user.Uid,
+		5: &user.Gid,
 		// "Sudo" boolean will be handled separately, so no 6
-		7: &currentUser.Password,
-		8: &currentHost,
+		7: &user.Password,
+		8: &line.host,
 	}
 
-	// Parse the record provided into our work vars
-	for i, element := range csvRecord {
-		// Handle variable fields separately
-		if i > 8 {
-			break
-		}
+	var fieldNames = map[int]string{
+		0: "firstname",
+		1: "lastname",
+		2: "username",
+		3: "email",
+		4: "unix_uid",
+		5: "unix_guid",
+		7: "password",
+	}
 
-		// Special case for sole boolean to be parsed
-		if i == 6 {
-			currentUser.Sudo = jcapi.GetTrueOrFalse(element)
-		} else if i == 3 {
-			*fieldMap[i] = strings.ToLower(element)
-		} else {
-			// Default case is to move the string into the var
-			*fieldMap[i] = element
+	// The user name, email and sudo flag are always applied, the other fields only when set
+	line.record.Fields = []string{"username", "email", "sudo"}
+
+	for i := 0; i <= 8; i++ {
+		switch i {
+		case 6:
+			user.Sudo = jcapi.GetTrueOrFalse(csvRecord[i])
+		case 3:
+			user.Email = strings.ToLower(csvRecord[i])
+		default:
+			*fieldMap[i] = csvRecord[i]
+
+			if fieldNames[i] != "" && csvRecord[i] != "" && i != 2 {
+				line.record.Fields = append(line.record.Fields, fieldNames[i])
+			}
 		}
 	}
 
-	// The administrators list is optional, and variable.  Using a slice
-	// will pick it up if it exists without causing errors otherwise.
-	// Map any names found to their ID values for later use.
-	adminsSlice := csvRecord[9:]
-
-	for _, tempAdmin := range adminsSlice {
-		// If there were no admins to slice, the slice itself will still have
-		// a single null element that is returned.  Account for that, but
-		// otherwise add-in the values found.
+	// The administrators list is optional, and variable.
+	for _, tempAdmin := range csvRecord[9:] {
 		if tempAdmin != "" {
-			currentAdmins[tempAdmin] = GetUserIdFromUserName(*userList, tempAdmin)
+			line.admins = append(line.admins, tempAdmin)
 		}
 	}
 
-	// Determine operation to perform based on whether the current user
-	// is already in JumpCloud...
-	var opCode jcapi.JCOp
-	currentUserId := GetUserIdFromUserName(*userList, currentUser.UserName)
+	// Sanity-check any UID/GID pair supplied and set management mode accordingly
+	if (user.Uid == "" && user.Gid != "") || (user.Uid != "" && user.Gid == "") {
+		line.err = fmt.Errorf("Could not process user '%s', err=Invalid UID:GID pair '%s:%s', both must be specified", user.UserName, user.Uid, user.Gid)
+		return
+	}
 
-	if currentUserId != "" {
-		opCode = jcapi.Update
-		currentUser.Id = currentUserId
-	} else {
-		opCode = jcapi.Insert
+	if user.Uid != "" {
+		user.EnableManagedUid = true
+		line.record.Fields = append(line.record.Fields, "enable_managed_uid")
 	}
 
-	// Sanity-check any UID/GID pair supplied and set management mode accordingly
-	if (currentUser.Uid == "" && currentUser.Gid != "") || (currentUser.Uid != "" && currentUser.Gid == "") {
-		err = fmt.Errorf("Could not process user '%s', err=Invalid UID:GID pair '%s:%s', both must be specified", currentUser.ToString(), currentUser.Uid, currentUser.Gid)
-		return
+	return
+}
+
+//
+// A bulk.Source over the lines of the CSV file. (helper type)
+//
+
+type csvLineSource struct {
+	lines []csvLine
+}
+
+func (s *csvLineSource) Next() (bulk.Record, error) {
+	if len(s.lines) == 0 {
+		return bulk.Record{}, io.EOF
 	}
 
-	if currentUser.Uid != "" || currentUser.Gid != "" {
-		currentUser.EnableManagedUid = true
+	line := s.lines[0]
+	s.lines = s.lines[1:]
+
+	if line.err != nil {
+		return bulk.Record{}, &bulk.RowError{Record: line.record, Err: line.err}
 	}
 
-	// Perform the requested operation on the current user and report results
-	currentUserId, err = jc.AddUpdateUser(opCode, currentUser)
+	return line.record, nil
+}
+
+//
+// Import the lines of the CSV file into JumpCloud, adding the users created to
+// userList, then create the tags of the lines imported, reporting on each line.
+// Unless dryRun is set, the report of the lines has the error of the tag of
+// each line, if any.
+//
 
+func ImportCSVLines(jc jcapi.JCAPI, userList *[]jcapi.JCUser, lines []csvLine, dryRun bool) (*bulk.Report, error) {
+	plan, err := bulk.NewPlan(&csvLineSource{lines: lines}, *userList, bulk.MatchUserName)
 	if err != nil {
-		err = fmt.Errorf("Could not %s user '%s', err='%s'", jcapi.MapJCOpToHTTP(opCode), currentUser.ToString(), err)
-		return
+		return nil, err
 	}
 
-	if opCode == jcapi.Update {
-		fmt.Printf("\tUser '%s' (ID '%s') updated from input file\n", currentUser.UserName, currentUserId)
+	var report *bulk.Report
+
+	if dryRun {
+		report = plan.DryRun()
 	} else {
-		fmt.Printf("\tLoaded user '%s' (ID '%s')\n", currentUser.UserName, currentUserId)
-		// Add this user to our list
-		currentUser.Id = currentUserId
-		*userList = append(*userList, currentUser)
+		report = plan.Apply(context.Background(), jc, 0)
 	}
 
-	// Create/associate JumpCloud tags for the host and user...
-	if currentHost != "" {
-		// Determine if the host specified is defined in JumpCloud
-		var currentJCSystem jcapi.JCSystem
-		var tempSysList []jcapi.JCSystem
+	for idx := range report.Results {
+		result := &report.Results[idx]
+		line := lines[idx]
 
-		tempSysList, err = jc.GetSystemByHostName(currentHost, true)
-		if err != nil {
-			err = fmt.Errorf("Look up for host '%s' failed - err='%s'", currentHost, err)
-			return
-		}
+		fmt.Printf("Line #%d:\n", result.Row)
 
 		switch {
-		case len(tempSysList) > 1:
-			err = fmt.Errorf("Found multiple hostnames for '%s', cannot build a tag for it.", currentHost)
-			return
-		case len(tempSysList) == 0:
-			err = fmt.Errorf("Could not find a system for '%s', cannot build a tag for this user.", currentHost)
-			return
-		case len(tempSysList) == 1:
-			currentJCSystem = tempSysList[0]
+		case result.Action == bulk.ActionError || result.Status == bulk.StatusFailed:
+			fmt.Printf("\tERROR: %s\n", result.Error)
+			continue
+		case result.Status == bulk.StatusSkipped:
+			continue
+		case dryRun:
+			fmt.Printf("\tUser '%s' would be %s\n", result.Key, plannedActions[result.Action])
+			continue
+		case result.Action == bulk.ActionCreate:
+			fmt.Printf("\tLoaded user '%s' (ID '%s')\n", result.Key, result.UserId)
+
+			// Add this user to our list
+			user := line.record.User
+			user.Id = result.UserId
+			*userList = append(*userList, user)
 		default:
-			return
+			fmt.Printf("\tUser '%s' (ID '%s') updated from input file\n", result.Key, result.UserId)
 		}
 
-		// Construct the user's tag from the inputs
-		var tempTag jcapi.JCTag
+		// Create/associate JumpCloud tags for the host and user...
+		if line.host != "" {
+			err = createUserTag(jc, *userList, line, result.UserId)
+			if err != nil {
+				result.Error = err.Error()
+				fmt.Printf("\tERROR: %s\n", err)
+			}
+		}
+	}
 
-		tempTag.Name = currentHost + " - "
+	return report, nil
+}
 
-		if currentUser.FirstName != "" && currentUser.LastName != "" {
-			tempTag.Name = tempTag.Name + currentUser.FirstName + " " + currentUser.LastName + " "
-		}
+// What a dry run reports for each action planned
+var plannedActions = map[bulk.Action]string{
+	bulk.ActionCreate: "loaded",
+	bulk.ActionUpdate: "updated",
+	bulk.ActionNone:   "left as it is",
+}
 
-		tempTag.Name = tempTag.Name + "(" + currentUser.UserName + ")"
+//
+// Process the line read from the CSV file into JumpCloud. (helper function)
+//
 
-		// Does the tag already exist?
-		var tag jcapi.JCTag
+func ProcessCSVRecord(jc jcapi.JCAPI, userList *[]jcapi.JCUser, csvRecord []string) error {
+	report, err := ImportCSVLines(jc, userList, []csvLine{parseCSVRecord(csvRecord)}, false)
+	if err != nil {
+		return err
+	}
 
-		tag, err = jc.GetTagByName(tempTag.Name)
-		if err != nil && !strings.Contains(err.Error(), "unexpected end of JSON input") {
-			err = fmt.Errorf("Tag lookup failed for tag '%s', skipping this tag, err='%s'", tempTag.Name, err)
-			return
-		}
+	if result := report.Results[0]; result.Error != "" {
+		return errors.New(result.Error)
+	}
 
-		if tag.Id != "" {
-			// Yep, tag exists
-			fmt.Printf("\tTag '%s' already exists, not modifying it.\n", tempTag.Name)
-			return
-		}
+	return nil
+}
 
-		opCode = jcapi.Insert
+//
+// Create the tag of the host for the user of the line, with the administrators
+// of the line, unless it already exists. (helper function)
+//
 
-		// Build a suitable tag from the request's elements
-		tempTag.ApplyToJumpCloud = true
-		tempTag.Systems = append(tempTag.Systems, currentJCSystem.Id)
-		tempTag.SystemUsers = append(tempTag.SystemUsers, currentUserId)
+func createUserTag(jc jcapi.JCAPI, userList []jcapi.JCUser, line csvLine, userId string) error {
+	user := line.record.User
 
-		for _, adminId := range currentAdmins {
-			tempTag.SystemUsers = append(tempTag.SystemUsers, adminId)
-		}
+	// Determine if the host specified is defined in JumpCloud
+	tempSysList, err := jc.GetSystemByHostName(line.host, true)
+	if err != nil {
+		return fmt.Errorf("Look up for host '%s' failed - err='%s'", line.host, err)
+	}
 
-		// Create or modify the tag in JumpCloud
-		tempTag.Id, err = jc.AddUpdateTag(opCode, tempTag)
-		if err != nil {
-			err = fmt.Errorf("Could not POST tag '%s', err='%s'", tempTag.ToString(), err)
-		} else {
-			fmt.Printf("\tCreated tag '%s' (ID '%s')\n", tempTag.Name, tempTag.Id)
-		}
+	switch {
+	case len(tempSysList) > 1:
+		return fmt.Errorf("Found multiple hostnames for '%s', cannot build a tag for it.", line.host)
+	case len(tempSysList) == 0:
+		return fmt.Errorf("Could not find a system for '%s', cannot build a tag for this user.", line.host)
 	}
 
-	return
+	// Construct the user's tag from the inputs
+	var tempTag jcapi.JCTag
+
+	tempTag.Name = line.host + " - "
+
+	if user.FirstName != "" && user.LastName != "" {
+		tempTag.Name = tempTag.Name + user.FirstName + " " + user.LastName + " "
+	}
+
+	tempTag.Name = tempTag.Name + "(" + user.UserName + ")"
+
+	// Does the tag already exist?
+	tag, err := jc.GetTagByName(tempTag.Name)
+	if err != nil && !strings.Contains(err.Error(), "unexpected end of JSON input") {
+		return fmt.Errorf("Tag lookup failed for tag '%s', skipping this tag, err='%s'", tempTag.Name, err)
+	}
+
+	if tag.Id != "" {
+		// Yep, tag exists
+		fmt.Printf("\tTag '%s' already exists, not modifying it.\n", tempTag.Name)
+		return nil
+	}
+
+	// Build a suitable tag from the request's elements
+	tempTag.ApplyToJumpCloud = true
+	tempTag.Systems = append(tempTag.Systems, tempSysList[0].Id)
+	tempTag.SystemUsers = append(tempTag.SystemUsers, userId)
+
+	for _, admin := range line.admins {
+		tempTag.SystemUsers = append(tempTag.SystemUsers, GetUserIdFromUserName(userList, admin))
+	}
+
+	// Create the tag in JumpCloud
+	tempTag.Id, err = jc.AddUpdateTag(jcapi.Insert, tempTag)
+	if err != nil {
+		return fmt.Errorf("Could not POST tag '%s', err='%s'", tempTag.ToString(), err)
+	}
+
+	fmt.Printf("\tCreated tag '%s' (ID '%s')\n", tempTag.Name, tempTag.Id)
+
+	return nil
+}
+
+//
+// Read the lines of the CSV file, a line that cannot be parsed failing on its
+// own. (helper function)
+//
+
+func readCSVLines(r io.Reader) ([]csvLine, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // indicates records have optional fields
+
+	var lines []csvLine
+
+	for {
+		record, err := reader.Read()
+
+		var parseErr *csv.ParseError
+
+		switch {
+		case err == io.EOF:
+			return lines, nil
+		case errors.As(err, &parseErr):
+			lines = append(lines, csvLine{err: err})
+		case err != nil:
+			return nil, err
+		default:
+			lines = append(lines, parseCSVRecord(record))
+		}
+	}
 }
 
 //
@@ -257,21 +384,42 @@ func main() {
 	// Input parameters
 	var apiKey string
 	var csvFile string
+	var bannedFile string
+	var dryRun bool
 
 	// Obtain the input parameters
 	flag.StringVar(&csvFile, "csv", "", "-csv=<filename>")
 	flag.StringVar(&apiKey, "key", "", "-key=<API-key-value>")
+	flag.StringVar(&bannedFile, "banned", "", "-banned=<filename of banned passwords, one per line>")
+	flag.BoolVar(&dryRun, "dry-run", false, "-dry-run to only report what would be imported")
 	flag.Parse()
 
 	if csvFile == "" || apiKey == "" {
 		fmt.Println("Usage of ./CSVImporter:")
 		fmt.Println("  -csv=\"\": -csv=<filename>")
 		fmt.Println("  -key=\"\": -key=<API-key-value>")
+		fmt.Println("  -banned=\"\": -banned=<filename of banned passwords, one per line>")
+		fmt.Println("  -dry-run: only report what would be imported")
 		return
 	}
 
+	// Check passwords locally before sending them
+	policy := jcapi.DefaultPasswordPolicy()
+
+	if bannedFile != "" {
+		err := policy.LoadBannedListFile(bannedFile)
+		if err != nil {
+			fmt.Printf("Could not load banned passwords, err='%s'\n", err)
+			return
+		}
+	}
+
 	// Attach to JumpCloud
-	jc := jcapi.NewJCAPI(apiKey, urlBase)
+	jc, err := jcapi.NewJCAPIWithOptions(apiKey, urlBase, jcapi.WithPasswordPolicy(policy))
+	if err != nil {
+		fmt.Printf("Could not connect to JumpCloud, err='%s'\n", err)
+		return
+	}
 
 	// Fetch all users in JumpCloud
 	userList, err := jc.GetSystemUsers(false)
@@ -291,39 +439,27 @@ func main() {
 
 	defer inFile.Close()
 
-	reader := csv.NewReader(inFile)
-	reader.FieldsPerRecord = -1 // indicates records have optional fields
-
-	// Process each user/request record found in the CSV file...
-	recordCount := 1
-
-	for {
-		// Read next record from CSV file
-		record, err := reader.Read()
+	lines, err := readCSVLines(inFile)
+	if err != nil {
+		fmt.Printf("ERROR: Could not read CSV file %s, err=%s\n", csvFile, err)
+		return
+	}
 
-		// Exit loop at the end of file or on error
-		if err == io.EOF {
-			fmt.Println("<EOF>")
-			break
-		} else if err != nil {
-			fmt.Printf("ERROR: Could not read CSV file %s, line %d, err=%s\n", csvFile, recordCount, err)
-			return
-		}
+	report, err := ImportCSVLines(jc, &userList, lines, dryRun)
+	if err != nil {
+		fmt.Printf("ERROR: Could not import CSV file %s, err=%s\n", csvFile, err)
+		return
+	}
 
-		fmt.Printf("Line #%d:\n", recordCount)
+	// Print run summary
+	summary := report.Summary()
 
-		// Process this request record
-		err = ProcessCSVRecord(jc, &userList, record)
-		if err != nil {
-			fmt.Printf("\tERROR: %s\n", err)
-		}
+	fmt.Printf("\n\nProcessed %d records from file %s: %d created, %d updated, %d unchanged, %d failed\n",
+		summary.Rows, csvFile, summary.Created, summary.Updated, summary.Unchanged, summary.Failed)
 
-		// Indicate that we processed another line of the CSV file
-		recordCount = recordCount + 1
+	if dryRun {
+		fmt.Println("NO ACTION TAKEN, run without -dry-run to import the users")
 	}
 
-	// Print run summary
-	fmt.Printf("\n\nProcessed %d records from file %s \n", recordCount-1, csvFile)
-
 	return
 }
@@ -1,36 +1,32 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
-	"fmt"
 	"log"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/TheJumpCloud/jcapi"
+	"github.com/TheJumpCloud/jcapi/reaper"
 )
 
 const (
 	URL_BASE string = "https://console.jumpcloud.com/api"
 )
 
-func dateBeforeNDays(date string, days int) (before bool, err error) {
-	dateField, err := time.Parse(time.RFC3339, date)
-	if err != nil {
-		err = fmt.Errorf("Could not parse date value '%s', err='%s'", date, err.Error())
-		return
-	}
-
-	before = dateField.Before(time.Now().Add(time.Duration(-days) * 24 * time.Hour))
-
-	return
-}
-
 func main() {
 	apiKey := flag.String("api-key", "", "Your JumpCloud Administrator API Key")
 	daysSinceLastConnection := flag.Int("days-since-last-connect", 30,
-		"Systems that have not connected in this many days or more, will be deleted from JumpCloud.")
-	enableDelete := flag.Bool("enable-delete", false, "Enable this flag to actually delete servers.")
+		"Systems that have not connected in this many days or more, will be quarantined, then deleted from JumpCloud.")
+	graceDays := flag.Int("grace-days", 7, "Systems are deleted on the first run after they have been in quarantine for this many days.")
+	maxDeletions := flag.Int("max-deletions", 0, "The most systems deleted in one run, 0 for no limit.")
+	excludeTags := flag.String("exclude-tags", "", "A comma-separated list of tags whose systems are never deleted.")
+	quarantineTag := flag.String("quarantine-tag", reaper.DefaultQuarantineTag, "The tag systems are put in before they are deleted.")
+	policyFile := flag.String("policy", "", "A JSON file with the policy, instead of the flags above.")
+	archiveDir := flag.String("archive-dir", "reaper-archive", "The directory the systems are saved to before they are quarantined.")
+	enableDelete := flag.Bool("enable-delete", false, "Enable this flag to actually quarantine and delete servers.")
 
 	flag.Parse()
 
@@ -38,41 +34,52 @@ func main() {
 		log.Fatalf("%s: You must specify an API key value (--api-key=keyValue)", os.Args[0])
 	}
 
+	policy := reaper.Policy{
+		InactiveDays:  *daysSinceLastConnection,
+		GraceDays:     *graceDays,
+		MaxDeletions:  *maxDeletions,
+		QuarantineTag: *quarantineTag,
+	}
+
+	if *excludeTags != "" {
+		policy.ExcludeTags = strings.Split(*excludeTags, ",")
+	}
+
+	if *policyFile != "" {
+		file, err := os.Open(*policyFile)
+		if err != nil {
+			log.Fatalf("Could not open policy file, err='%s'", err)
+		}
+
+		policy, err = reaper.LoadPolicy(file)
+		file.Close()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	archive, err := reaper.NewDirArchive(*archiveDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	jc := jcapi.NewJCAPI(*apiKey, URL_BASE)
 
-	// Get all the systems in the account
-	systems, err := jc.GetSystems(false)
+	report, err := reaper.Reaper{Policy: policy, Archive: archive, DryRun: !*enableDelete}.Run(context.Background(), jc)
 	if err != nil {
-		log.Fatalf("Could not get all systems in the account, err='%s'", err.Error())
+		log.Fatalf("Could not reap the inactive systems of the account, err='%s'", err)
 	}
 
-	for _, system := range systems {
-		if system.Active == false {
-			var okToDelete bool
-
-			if system.LastContact == "" {
-				okToDelete = true
-			} else {
-				okToDelete, err = dateBeforeNDays(system.LastContact, *daysSinceLastConnection)
-				if err != nil {
-					log.Fatalf("Could not compare date '%s' for system ID '%s' (%s), err='%s'", system.LastContact, system.Id, system.Hostname, err.Error())
-				}
-			}
-
-			if okToDelete {
-				fmt.Printf("Deleting [%s] - ", system.ToString())
-
-				if *enableDelete {
-					err = jc.DeleteSystem(system)
-					if err != nil {
-						log.Fatalf("Delete failed, err='%s'\n", err.Error())
-					} else {
-						fmt.Printf("SUCCESS!\n")
-					}
-				} else {
-					fmt.Printf("NO ACTION TAKEN, use --enable-delete to actually delete servers\n")
-				}
-			}
-		}
+	// The audit report goes to stdout, one system per line, and the summary to stderr
+	if err := report.WriteJSON(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+
+	summary, _ := json.Marshal(report.Summary())
+	log.Printf("%s", summary)
+
+	if !*enableDelete {
+		log.Printf("NO ACTION TAKEN, use --enable-delete to actually quarantine and delete servers")
 	}
 }
@@ -1,16 +1,15 @@
 package main
 
 import (
-	"encoding/csv"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/TheJumpCloud/jcapi"
+	"github.com/TheJumpCloud/jcapi/export"
 )
 
 // URLBase is the production api endpoint.
@@ -18,18 +17,42 @@ const URLBase string = "https://console.jumpcloud.com/api"
 
 var api jcapi.JCAPI
 
+// systemUser is a row of the report: a user the command found on a system
+type systemUser struct {
+	systemID    string
+	hostname    string
+	username    string
+	requestTime jcapi.Timestamp
+}
+
+// systemUserColumns are the columns of the report, under their historical headers
+var systemUserColumns = []export.Column{
+	export.NewColumn("SYSTEM ID", func(record interface{}) string { return record.(systemUser).systemID }),
+	export.NewColumn("HOSTNAME", func(record interface{}) string { return record.(systemUser).hostname }),
+	export.NewColumn("USERNAME", func(record interface{}) string { return record.(systemUser).username }),
+	export.NewColumn("JUMPCLOUD USERNAME", func(record interface{}) string { return "" }),
+	export.NewColumn("COMMAND REQUEST TIME", func(record interface{}) string { return record.(systemUser).requestTime.String() }),
+}
+
 func main() {
 	var apiKey string
 	var commandID string
 	var url string
 	var outfile string
+	var formatName string
 
 	flag.StringVar(&apiKey, "key", "", "Your JumpCloud Administrator API Key")
 	flag.StringVar(&commandID, "commandid", "", "The id of the command to run")
 	flag.StringVar(&outfile, "out", "", "File path for CSV output")
 	flag.StringVar(&url, "url", URLBase, "Alternative Jumpcloud API URL (optional)")
+	flag.StringVar(&formatName, "format", "csv", "The format of the output: csv, tsv, jsonl or yaml (optional)")
 	flag.Parse()
 
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	if apiKey == "" {
 		log.Fatalln("API key must be provided.")
 	}
@@ -54,7 +77,7 @@ func main() {
 		}
 		output, err = getFileWriter(path)
 		if err != nil {
-			log.Fatalln("Problem with the outfile: %s", err.Error)
+			log.Fatalf("Problem with the outfile: %s", err)
 		}
 	} else {
 		output = os.Stdout
@@ -62,8 +85,8 @@ func main() {
 
 	defer output.Close()
 
-	if err := writeResultsToCSV(results, hostnameMap, output); err != nil {
-		log.Fatalln("Error writing to csv: %s", err.Error())
+	if err := writeResults(results, hostnameMap, export.NewWriter(output, format, systemUserColumns)); err != nil {
+		log.Fatalf("Error writing to csv: %s", err)
 	}
 }
 
@@ -75,28 +98,21 @@ func getFileWriter(absPath string) (*os.File, error) {
 	return os.Create(absPath)
 }
 
-func writeResultsToCSV(results []jcapi.JCCommandResult, hostnameMap map[string]string, writer io.Writer) error {
-	w := csv.NewWriter(writer)
-
-	if err := w.Write([]string{"SYSTEM ID", "HOSTNAME", "USERNAME", "JUMPCLOUD USERNAME", "COMMAND REQUEST TIME"}); err != nil {
-		return err
-	}
-	w.Flush()
-
+// writeResults writes a row for each line of the output of the results, then flushes w
+func writeResults(results []jcapi.JCCommandResult, hostnameMap map[string]string, w *export.Writer) error {
 	for _, result := range results {
 		lines := strings.Split(result.Response.Data.Output, "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
 			if line != "" {
-				if err := w.Write([]string{result.System, hostnameMap[result.System], line, "", result.RequestTime}); err != nil {
+				if err := w.Write(systemUser{result.System, hostnameMap[result.System], line, result.RequestTime}); err != nil {
 					return err
 				}
-
 			}
 		}
-		w.Flush()
 	}
-	return nil
+
+	return w.Flush()
 }
 
 func getHostnameForID(systemID string) (string, error) {
@@ -112,8 +112,8 @@ func main() {
 
 	for _, system := range systems {
 		outLine := []string{system.Id, system.DisplayName, system.Hostname, fmt.Sprintf("%t", system.Active),
-			system.AmazonInstanceID, system.Os, system.Version, system.AgentVersion, system.Created,
-			system.LastContact}
+			system.AmazonInstanceID, system.Os, system.Version, system.AgentVersion, system.Created.String(),
+			system.LastContact.String()}
 
 		if isGroups {
 			// for a Groups org, let's retrieve the system groups this system is a member of:
@@ -116,8 +116,8 @@ func main() {
 	for _, system := range systems {
 
 		outLine := []string{system.Id, system.DisplayName, system.Hostname, fmt.Sprintf("%t", system.Active),
-			system.AmazonInstanceID, system.Os, system.Version, system.AgentVersion, system.Created,
-			system.LastContact}
+			system.AmazonInstanceID, system.Os, system.Version, system.AgentVersion, system.Created.String(),
+			system.LastContact.String()}
 
 		var userIds []string
 
@@ -6,35 +6,86 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/TheJumpCloud/jcapi"
 	jcapiv2 "github.com/TheJumpCloud/jcapi-go/v2"
+	"github.com/TheJumpCloud/jcapi/export"
 )
 
 const (
 	apiUrlDefault string = "https://console.jumpcloud.com/api"
 )
 
-func outFirst(data string) {
-	fmt.Printf("\"%s\"", data)
-}
+// userColumns returns the columns of the export, under their historical headers
+func userColumns() []export.Column {
+	columns, err := export.UserColumns("username", "firstname", "lastname", "email", "unix_uid", "unix_guid", "activated", "password_expired", "sudo")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for idx, header := range []string{"Username", "FirstName", "LastName", "Email", "UID", "GID", "Activated", "PasswordExpired", "Sudo"} {
+		columns[idx] = columns[idx].As(header)
+	}
 
-func out(data string) {
-	fmt.Printf(",\"%s\"", data)
+	return columns
 }
 
-func endLine() {
-	fmt.Printf("\n")
+//
+// userGroupsColumn returns the column of the IDs of the User Groups each user is a member
+// of, read with the API v2 as the users are written.
+//
+// NOTE: there are many more associations for a user in a Groups org we may want to list here as well:
+// Applications, Directories, GSuite, LDAP, O365, Systems, Radius Servers
+//
+func userGroupsColumn(apiClientV2 *jcapiv2.APIClient, auth context.Context) export.Column {
+	return export.NewColumn("User Groups", func(record interface{}) string {
+		user := record.(jcapi.JCUser)
+
+		var ids []string
+
+		for skip := 0; ; skip += searchSkipInterval {
+			// set up optional parameters:
+			optionals := map[string]interface{}{
+				"limit": int32(searchLimit),
+				"skip":  int32(skip),
+			}
+
+			graphs, _, err := apiClientV2.UsersApi.GraphUserMemberOf(auth, user.Id, contentType, accept, optionals)
+			if err != nil {
+				log.Printf("Could not read groups for user %s, err='%s'\n", user.Id, err)
+				break
+			}
+
+			for _, graph := range graphs {
+				ids = append(ids, graph.Id)
+			}
+
+			if len(graphs) < searchLimit {
+				break
+			}
+		}
+
+		return strings.Join(ids, export.ListSeparator)
+	})
 }
 
 func main() {
 	var apiKey string
 	var apiUrl string
+	var formatName string
+
 	// Obtain the input parameters
 	flag.StringVar(&apiKey, "key", "", "-key=<API-key-value>")
 	flag.StringVar(&apiUrl, "url", apiUrlDefault, "-url=<jumpcloud-api-url>")
+	flag.StringVar(&formatName, "format", "csv", "-format=<csv|tsv|jsonl|yaml> (optional)")
 	flag.Parse()
 
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// if the api key isn't specified, try to obtain it through environment variable:
 	if apiKey == "" {
 		apiKey = os.Getenv(apiKeyEnvVariable)
@@ -44,6 +95,7 @@ func main() {
 		fmt.Println("Usage:")
 		fmt.Println("  -key=\"\": -key=<API-key-value>")
 		fmt.Println("  -url=\"\": -url=<jumpcloud-api-url> (optional)")
+		fmt.Println("  -format=\"csv\": -format=<csv|tsv|jsonl|yaml> (optional)")
 		fmt.Println("You can also set the API key via the JUMPCLOUD_APIKEY environment variable:")
 		fmt.Println("Run: export JUMPCLOUD_APIKEY=<your-JumpCloud-API-key>")
 		return
@@ -55,84 +107,33 @@ func main() {
 		log.Fatalf("Could not determine your org type, err='%s'\n", err)
 	}
 
-	// if we're on a groups org, instantiate API client v2:
-	var apiClientV2 *jcapiv2.APIClient
-	var auth context.Context
+	columns := userColumns()
+
+	// The last column lists the User Groups of each user on a groups org, and their tags otherwise
 	if isGroups {
-		apiClientV2 = jcapiv2.NewAPIClient(jcapiv2.NewConfiguration())
+		apiClientV2 := jcapiv2.NewAPIClient(jcapiv2.NewConfiguration())
 		apiClientV2.ChangeBasePath(apiUrl + "/v2")
 		// set up the API key via context:
-		auth = context.WithValue(context.TODO(), jcapiv2.ContextAPIKey, jcapiv2.APIKey{
+		auth := context.WithValue(context.TODO(), jcapiv2.ContextAPIKey, jcapiv2.APIKey{
 			Key: apiKey,
 		})
+
+		columns = append(columns, userGroupsColumn(apiClientV2, auth))
+	} else {
+		tags, err := export.UserColumns(export.TagNamesColumn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		columns = append(columns, tags[0].As("Tags"))
 	}
 
 	// instantiate the API client v1:
 	apiClientV1 := jcapi.NewJCAPI(apiKey, apiUrl)
 
-	// Grab all system users (with their tags if this is a Tags org):
-	userList, err := apiClientV1.GetSystemUsers(!isGroups)
+	// Write all system users as they are fetched, with their tags if this is a Tags org:
+	err = export.Users(context.Background(), apiClientV1, export.NewWriter(os.Stdout, format, columns))
 	if err != nil {
-		log.Fatalf("Could not read system users, err='%s'\n", err)
-	}
-
-	outFirst("Username")
-	out("FirstName")
-	out("LastName")
-	out("Email")
-	out("UID")
-	out("GID")
-	out("Activated")
-	out("PasswordExpired")
-	out("Sudo")
-	if isGroups {
-		out("User Groups")
-	} else {
-		out("Tags")
-	}
-	endLine()
-
-	for _, user := range userList {
-		outFirst(user.UserName)
-		out(user.FirstName)
-		out(user.LastName)
-		out(user.Email)
-		out(user.Uid)
-		out(user.Gid)
-		out(fmt.Sprintf("%t", user.Activated))
-		out(fmt.Sprintf("%t", user.PasswordExpired))
-		out(fmt.Sprintf("%t", user.Sudo))
-
-		if isGroups {
-			// For now, just list the User Groups this user is a member of.
-			// NOTE: there are many more associations for a user in a Groups org we may want to list here as well:
-			// Applications, Directories, GSuite, LDAP, O365, Systems, Radius Servers
-
-			var graphs []jcapiv2.GraphObjectWithPaths
-			for skip := 0; skip == 0 || len(graphs) == searchLimit; skip += searchSkipInterval {
-				// set up optional parameters:
-				optionals := map[string]interface{}{
-					"limit": int32(searchLimit),
-					"skip":  int32(skip),
-				}
-				graphs, _, err := apiClientV2.UsersApi.GraphUserMemberOf(auth, user.Id, contentType, accept, optionals)
-
-				if err != nil {
-					log.Printf("Could not read groups for user %s, err='%s'\n", user.Id, err)
-					continue
-				}
-				// output the ids for each user group we retrieved:
-				for _, graph := range graphs {
-					out(graph.Id)
-				}
-			}
-		} else {
-			// this is a Tags org, just list the Tags we've already retrieved:
-			for _, tag := range user.Tags {
-				out(tag.Name)
-			}
-		}
-
-		endLine()
+		log.Fatalf("Could not export system users, err='%s'\n", err)
 	}
 }
@@ -3,12 +3,10 @@ package main
 import (
 	"bufio"
 	"encoding/csv"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
 	"time"
 
 	"github.com/TheJumpCloud/jcapi"
@@ -34,13 +32,9 @@ func getUserByEmail(jc jcapi.JCAPI, email string) ([]jcapi.JCUser, error) {
 
 func buildAttributes(user jcapi.JCUser, userRecord []string, attributeNames []string) userAttributes {
 
-	// build map of existing user attributes
-	attributeMap := make(map[string]jcapi.JCUserAttribute)
-	for _, attribute := range user.Attributes {
-		attributeMap[attribute.Name] = attribute
-	}
-
 	// add or overwrite attributes from file record
+	var merge jcapi.AttributeMerge
+
 	recordLen := len(userRecord)
 	for i, attributeName := range attributeNames {
 		attribute := jcapi.JCUserAttribute{Name: attributeName}
@@ -48,26 +42,16 @@ func buildAttributes(user jcapi.JCUser, userRecord []string, attributeNames []st
 		if recordLen > (i + 1) {
 			attribute.Value = userRecord[i+1]
 		}
-		attributeMap[attributeName] = attribute
-	}
-
-	attributeArray := make([]jcapi.JCUserAttribute, 0, len(attributeMap))
-	for _, attribute := range attributeMap {
-		attributeArray = append(attributeArray, attribute)
+		merge.Set = append(merge.Set, attribute)
 	}
 
-	return userAttributes{attributeArray}
+	return userAttributes{jcapi.MergeAttributes(user.Attributes, merge)}
 }
 
 func importUserAttributes(jc jcapi.JCAPI, user jcapi.JCUser, attributes userAttributes) error {
 
-	b, err := json.Marshal(attributes)
-	if err != nil {
-		return fmt.Errorf("Error converting attributes to JSON: %s", err.Error())
-	}
-
-	url := "/systemusers/" + user.Id
-	_, jcErr := jc.Put(url, b)
+	// Only send the attributes, so the rest of the user is left as it is
+	_, jcErr := jc.UpdateUserFields(user.Id, jcapi.JCUserChanges{"attributes": attributes.Attributes})
 	if jcErr != nil {
 		return fmt.Errorf("Error setting attribute(s) on user %s: %s", user.Email, jcErr.Error())
 	}
@@ -77,19 +61,9 @@ func importUserAttributes(jc jcapi.JCAPI, user jcapi.JCUser, attributes userAttr
 
 func validateAttributeNames(attributeNames []string) error {
 
-	// 0 < length < 32
-	// Alphanumeric, no spaces
-	isAlphaNumeric := regexp.MustCompile(`^[0-9A-Za-z]+$`).MatchString
 	for _, attributeName := range attributeNames {
-		nameLen := len(attributeName)
-		if nameLen == 0 {
-			return fmt.Errorf("Attribute name is empty")
-		}
-		if nameLen > 32 {
-			return fmt.Errorf("Attribute name exceeds 32 characters [%s]", attributeName)
-		}
-		if !isAlphaNumeric(attributeName) {
-			return fmt.Errorf("Attribute name contains non-alphanumeric characters or spaces [%s]", attributeName)
+		if err := jcapi.ValidateAttributeName(attributeName); err != nil {
+			return err
 		}
 	}
 
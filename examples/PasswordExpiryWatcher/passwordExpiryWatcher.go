@@ -1,32 +1,71 @@
 package main
 
 import (
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
-	"time"
 
 	"github.com/TheJumpCloud/jcapi"
+	"github.com/TheJumpCloud/jcapi/export"
 )
 
+// passwordExpiryColumns returns the columns of the report, under their historical headers
+func passwordExpiryColumns() []export.Column {
+	columns, err := export.UserColumns("firstname", "lastname", "email", "enable_user_portal_multifactor", "totp_enabled")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expiryDate := export.NewColumn("PASSWORD EXPIRY DATE", func(record interface{}) string {
+		// Keep the layout of time.Time.String(), which the readers of the report parse
+		if date := record.(jcapi.JCUser).PasswordExpirationDate; !date.IsZero() {
+			return date.Time().String()
+		}
+		return "No Date Set"
+	})
+
+	expired := export.NewColumn("PASSWORD EXPIRED", func(record interface{}) string {
+		if record.(jcapi.JCUser).PasswordExpired {
+			return "YES"
+		}
+		return "NO"
+	})
+
+	return []export.Column{
+		columns[0].As("FIRSTNAME"),
+		columns[1].As("LASTNAME"),
+		columns[2].As("EMAIL"),
+		expiryDate,
+		expired,
+		columns[3].As("MFA ENABLED"),
+		columns[4].As("MFA VERIFIED"),
+	}
+}
+
 func main() {
 	// Input parameters
 	var apiKey string
 	var csvFile string
+	var formatName string
 
 	// Obtain the input parameters
 	flag.StringVar(&csvFile, "output", "", "-output=<filename>")
 	flag.StringVar(&apiKey, "key", "", "-key=<API-key-value>")
+	flag.StringVar(&formatName, "format", "csv", "-format=<csv|tsv|jsonl|yaml> (optional)")
 	flag.Parse()
 
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	if csvFile == "" || apiKey == "" {
 		fmt.Println("Usage of ./CSVImporter:")
 		fmt.Println("  -output=\"\": -output=<filename>")
 		fmt.Println("  -key=\"\": -key=<API-key-value>")
+		fmt.Println("  -format=\"csv\": -format=<csv|tsv|jsonl|yaml> (optional)")
 		return
 	}
 
@@ -51,32 +90,16 @@ func main() {
 		log.Fatal(err)
 	}
 	defer file.Close()
-	w := csv.NewWriter(file)
 
-	if err := w.Write([]string{"FIRSTNAME", "LASTNAME", "EMAIL", "PASSWORD EXPIRY DATE", "PASSWORD EXPIRED", "MFA ENABLED", "MFA VERIFIED"}); err != nil {
-		log.Fatalln("error writing record to csv:", err)
-	}
+	w := export.NewWriter(file, format, passwordExpiryColumns())
 
 	for _, record := range userList {
-		nullTime := time.Time{}
-		var expired, passwordExpirationString string
-		if record.PasswordExpired {
-			expired = "YES"
-		} else {
-			expired = "NO"
-		}
-		if record.PasswordExpirationDate == nullTime {
-			passwordExpirationString = "No Date Set"
-		} else {
-			passwordExpirationString = record.PasswordExpirationDate.String()
-		}
-		if err := w.Write([]string{record.FirstName, record.LastName, record.Email, passwordExpirationString, expired, strconv.FormatBool(record.EnableUserPortalMultifactor), strconv.FormatBool(record.TotpEnabled)}); err != nil {
+		if err := w.Write(record); err != nil {
 			log.Fatalln("error writing record to csv:", err)
 		}
 	}
-	w.Flush()
 
-	if err := w.Error(); err != nil {
+	if err := w.Flush(); err != nil {
 		log.Fatal(err)
 	}
 
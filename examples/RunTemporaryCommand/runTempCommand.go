@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
 	"time"
 
@@ -66,22 +65,6 @@ func deleteCommandResultsByName(jc jcapi.JCAPI, commandName string) (err error)
 	return
 }
 
-func findSystemsByOSType(systems []jcapi.JCSystem, osTypeRegEx string) (indices []int, err error) {
-	r, err := regexp.Compile(osTypeRegEx)
-	if err != nil {
-		err = fmt.Errorf("Could not compile regex for '%s', err='%s'", osTypeRegEx, err.Error())
-		return
-	}
-
-	for idx, system := range systems {
-		if r.Match([]byte(system.Os)) && system.Active {
-			indices = append(indices, idx)
-		}
-	}
-
-	return
-}
-
 func getSystemNameMap(jc jcapi.JCAPI, idList []string) (systemNameMap map[string]jcapi.JCSystem, err error) {
 	systemNameMap = make(map[string]jcapi.JCSystem)
 
@@ -234,27 +217,22 @@ func main() {
 	//
 	// Get the list of matching servers and add them to the command
 	//
-	systems, err := jc.GetSystems(false)
-	if err != nil {
-		log.Fatalf("Could not get a list of all systems, err='%s'")
-	}
-
-	indices, err := findSystemsByOSType(systems, *osType)
+	systems, err := jc.FindSystems(jcapi.SystemQuery{OSPattern: *osType, Active: jcapi.OnlyActive}, false)
 	if err != nil {
-		log.Fatalf("Could not search a list of systems for OS type matching '%s', err='%s'", *osType, err.Error())
+		log.Fatalf("Could not search systems for OS type matching '%s', err='%s'", *osType, err.Error())
 	}
 
-	if len(indices) == 0 {
+	if len(systems) == 0 {
 		log.Fatalf("No systems match '%s' on your JumpCloud account\n", *osType)
 	}
 
 	fmt.Printf("Executing Command on the Following Systems\n")
 	fmt.Printf("------------------------------------------\n")
 
-	for _, index := range indices {
-		fmt.Printf("%s\t%s\n", systems[index].Id, systems[index].Hostname)
+	for _, system := range systems {
+		fmt.Printf("%s\t%s\n", system.Id, system.Hostname)
 
-		commandObj.Systems = append(commandObj.Systems, systems[index].Id)
+		commandObj.Systems = append(commandObj.Systems, system.Id)
 	}
 
 	//
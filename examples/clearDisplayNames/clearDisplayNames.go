@@ -30,11 +30,11 @@ func main() {
 		if systemsList[idx].DisplayName != systemsList[idx].Hostname {
 			fmt.Printf("Resetting display name (%s) to '%s'\n", systemsList[idx].DisplayName, systemsList[idx].Hostname)
 
-			systemsList[idx].DisplayName = systemsList[idx].Hostname
+			changes := jcapi.JCSystemChanges{}.SetDisplayName(systemsList[idx].Hostname)
 
-			_, err := jc.UpdateSystem(systemsList[idx])
+			_, err := jc.UpdateSystemFields(systemsList[idx].Id, changes)
 			if err != nil {
-				fmt.Printf("ERROR: Could not update system '%s', err='%s' - SKIPPING\n", err.Error())
+				fmt.Printf("ERROR: Could not update system '%s', err='%s' - SKIPPING\n", systemsList[idx].Hostname, err.Error())
 			}
 		}
 	}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+const (
+	// Separates the items of a list in a single cell, such as the tags of a user
+	ListSeparator string = ";"
+
+	// The prefix of the columns holding one user attribute, as in "attributes.costCenter"
+	AttributeColumnPrefix string = "attributes."
+
+	// The column of the names of the tags a user or system is in
+	TagNamesColumn string = "tagNames"
+)
+
+// The columns exported when none are selected
+var (
+	DefaultUserColumns   = []string{"username", "firstname", "lastname", "email", "unix_uid", "unix_guid", "activated", "password_expired", "sudo", TagNamesColumn}
+	DefaultSystemColumns = []string{"_id", "hostname", "displayName", "os", "version", "arch", "agentVersion", "active", "lastContact", "remoteIP", TagNamesColumn}
+	DefaultTagColumns    = []string{"_id", "name", "groupname", "systems", "systemusers", "expirationTime", "expired"}
+)
+
+//
+// Column is a column of an export: its name, used as the header of CSV and TSV and as the
+// key in JSON Lines and YAML, and how to get its value from a record. Build them with
+// UserColumns(), SystemColumns(), TagColumns() or NewColumn().
+//
+type Column struct {
+	Name string
+
+	recordType reflect.Type // the type of the records, nil when any record is accepted
+	needsTags  bool         // the value is read from the Tags of the record
+	value      func(record reflect.Value) string
+}
+
+// NewColumn returns a column whose value is computed by value, for any type of record.
+func NewColumn(name string, value func(record interface{}) string) Column {
+	return Column{
+		Name: name,
+		value: func(record reflect.Value) string {
+			return value(record.Interface())
+		},
+	}
+}
+
+// As returns the column renamed to name, e.g. to keep the headers of an existing report.
+func (c Column) As(name string) Column {
+	c.Name = name
+	return c
+}
+
+//
+// UserColumns returns the columns of jcapi.JCUser records with the given names, or
+// DefaultUserColumns when there are none. A name is either the JSON name of a field of
+// jcapi.JCUser, "attributes.<name>" for the value of one attribute, or TagNamesColumn.
+//
+func UserColumns(names ...string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultUserColumns
+	}
+
+	return columnsOf(reflect.TypeOf(jcapi.JCUser{}), names)
+}
+
+// SystemColumns returns the columns of jcapi.JCSystem records, or DefaultSystemColumns.
+func SystemColumns(names ...string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultSystemColumns
+	}
+
+	return columnsOf(reflect.TypeOf(jcapi.JCSystem{}), names)
+}
+
+// TagColumns returns the columns of jcapi.JCTag records, or DefaultTagColumns.
+func TagColumns(names ...string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultTagColumns
+	}
+
+	return columnsOf(reflect.TypeOf(jcapi.JCTag{}), names)
+}
+
+func columnsOf(recordType reflect.Type, names []string) ([]Column, error) {
+	columns := make([]Column, len(names))
+
+	for idx, name := range names {
+		column, err := columnOf(recordType, strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		columns[idx] = column
+	}
+
+	return columns, nil
+}
+
+func columnOf(recordType reflect.Type, name string) (Column, error) {
+	column := Column{Name: name, recordType: recordType}
+
+	switch {
+	case name == TagNamesColumn:
+		field, ok := recordType.FieldByName("Tags")
+		if !ok || field.Type != reflect.TypeOf([]jcapi.JCTag{}) {
+			return Column{}, fmt.Errorf("ERROR: Column '%s' is not available for %s", name, recordType.Name())
+		}
+
+		column.needsTags = true
+		column.value = func(record reflect.Value) string {
+			var names []string
+			for _, tag := range record.FieldByIndex(field.Index).Interface().([]jcapi.JCTag) {
+				names = append(names, tag.Name)
+			}
+
+			return strings.Join(names, ListSeparator)
+		}
+
+		return column, nil
+	case strings.HasPrefix(name, AttributeColumnPrefix) && recordType == reflect.TypeOf(jcapi.JCUser{}):
+		attribute := strings.TrimPrefix(name, AttributeColumnPrefix)
+		if err := jcapi.ValidateAttributeName(attribute); err != nil {
+			return Column{}, err
+		}
+
+		column.value = func(record reflect.Value) string {
+			value, _ := record.Interface().(jcapi.JCUser).Attribute(attribute)
+			return value
+		}
+
+		return column, nil
+	}
+
+	for idx := 0; idx < recordType.NumField(); idx++ {
+		field := recordType.Field(idx)
+
+		if strings.TrimSpace(strings.Split(field.Tag.Get("json"), ",")[0]) == name && name != "" && name != "-" {
+			column.value = func(record reflect.Value) string {
+				return formatValue(record.Field(idx))
+			}
+
+			return column, nil
+		}
+	}
+
+	return Column{}, fmt.Errorf("ERROR: %s has no field named '%s'", recordType.Name(), name)
+}
+
+//
+// formatValue returns the value of a field as a single cell: times as the API returned
+// them (empty when not set), lists of strings joined with ListSeparator, user attributes
+// as name=value pairs, and other lists and structures in JSON.
+//
+func formatValue(value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case jcapi.Timestamp:
+		return v.String()
+	case []string:
+		return strings.Join(v, ListSeparator)
+	case []jcapi.JCUserAttribute:
+		pairs := make([]string, len(v))
+		for idx, attribute := range v {
+			pairs[idx] = attribute.Name + "=" + attribute.Value
+		}
+		return strings.Join(pairs, ListSeparator)
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return ""
+		}
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
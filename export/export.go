@@ -0,0 +1,264 @@
+//
+// Package export writes JumpCloud users, systems and tags out as CSV, TSV, JSON Lines or
+// YAML, one record at a time, with the columns selected by the caller in a stable order.
+//
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Format is the output format of a Writer
+type Format int
+
+const (
+	CSV       Format = iota
+	TSV              // tab separated, tabs and line breaks in values replaced by spaces
+	JSONLines        // one JSON object per record, with the columns as keys
+	YAML             // a sequence of mappings, with the columns as keys
+)
+
+var formatNames = map[Format]string{CSV: "csv", TSV: "tsv", JSONLines: "jsonl", YAML: "yaml"}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat returns the format of the given name: csv, tsv, jsonl (or json) and yaml (or yml).
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return CSV, nil
+	case "tsv":
+		return TSV, nil
+	case "jsonl", "json":
+		return JSONLines, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+
+	return CSV, fmt.Errorf("ERROR: Unknown export format '%s'", name)
+}
+
+// Keys written as they are in YAML, the others are quoted
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_.]*$`)
+
+// tsvReplacer keeps values on a single TSV cell
+var tsvReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+//
+// Writer writes records as rows of the chosen columns. The header, for the formats that
+// have one, is written along with the first record, or by Flush() when there are none.
+// Writer is not safe for concurrent use.
+//
+type Writer struct {
+	out     *bufio.Writer
+	csv     *csv.Writer
+	format  Format
+	columns []Column
+	count   int
+	started bool
+}
+
+// NewWriter returns a writer of the given columns to w, in format.
+func NewWriter(w io.Writer, format Format, columns []Column) *Writer {
+	writer := &Writer{out: bufio.NewWriter(w), format: format, columns: columns}
+
+	if format == CSV {
+		writer.csv = csv.NewWriter(writer.out)
+	}
+
+	return writer
+}
+
+// Columns returns the columns the writer writes.
+func (w *Writer) Columns() []Column {
+	return w.columns
+}
+
+// Count returns the number of records written so far.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// needsTags reports whether any column reads the tags of the records
+func (w *Writer) needsTags() bool {
+	for _, column := range w.columns {
+		if column.needsTags {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Write writes record, a value or a pointer of the type the columns were built for.
+func (w *Writer) Write(record interface{}) error {
+	value := reflect.ValueOf(record)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || value.Kind() == reflect.Ptr {
+		return fmt.Errorf("ERROR: Cannot export a nil record")
+	}
+
+	cells := make([]string, len(w.columns))
+
+	for idx, column := range w.columns {
+		if column.recordType != nil && value.Type() != column.recordType {
+			return fmt.Errorf("ERROR: Column '%s' is for %s records, not %s", column.Name, column.recordType.Name(), value.Type())
+		}
+
+		cells[idx] = column.value(value)
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	if err := w.writeRow(cells); err != nil {
+		return fmt.Errorf("ERROR: Could not write record %d, err='%w'", w.count+1, err)
+	}
+
+	w.count++
+
+	return nil
+}
+
+func (w *Writer) writeHeader() error {
+	if w.started {
+		return nil
+	}
+
+	w.started = true
+
+	names := make([]string, len(w.columns))
+	for idx, column := range w.columns {
+		names[idx] = column.Name
+	}
+
+	var err error
+
+	switch w.format {
+	case CSV:
+		err = w.csv.Write(names)
+	case TSV:
+		err = w.writeTSV(names)
+	}
+
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not write header, err='%w'", err)
+	}
+
+	return nil
+}
+
+func (w *Writer) writeRow(cells []string) error {
+	switch w.format {
+	case CSV:
+		return w.csv.Write(cells)
+	case TSV:
+		return w.writeTSV(cells)
+	case JSONLines:
+		return w.writeJSON(cells)
+	case YAML:
+		return w.writeYAML(cells)
+	}
+
+	return fmt.Errorf("unknown format %s", w.format)
+}
+
+func (w *Writer) writeTSV(cells []string) error {
+	for idx, cell := range cells {
+		if idx > 0 {
+			w.out.WriteByte('\t')
+		}
+
+		w.out.WriteString(tsvReplacer.Replace(cell))
+	}
+
+	_, err := w.out.WriteString("\n")
+	return err
+}
+
+// writeJSON writes the cells as an object whose keys are in the order of the columns
+func (w *Writer) writeJSON(cells []string) error {
+	w.out.WriteByte('{')
+
+	for idx, cell := range cells {
+		if idx > 0 {
+			w.out.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(w.columns[idx].Name)
+		value, _ := json.Marshal(cell)
+
+		w.out.Write(key)
+		w.out.WriteByte(':')
+		w.out.Write(value)
+	}
+
+	_, err := w.out.WriteString("}\n")
+	return err
+}
+
+// writeYAML writes the cells as an item of a sequence, with JSON strings as scalars
+func (w *Writer) writeYAML(cells []string) error {
+	for idx, cell := range cells {
+		prefix := "  "
+		if idx == 0 {
+			prefix = "- "
+		}
+
+		key := w.columns[idx].Name
+		if !plainYAMLKey.MatchString(key) {
+			data, _ := json.Marshal(key)
+			key = string(data)
+		}
+
+		value, _ := json.Marshal(cell)
+
+		if _, err := fmt.Fprintf(w.out, "%s%s: %s\n", prefix, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the header if no record was written, and any buffered data.
+func (w *Writer) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	if w.format == YAML && w.count == 0 {
+		w.out.WriteString("[]\n")
+	}
+
+	if w.csv != nil {
+		w.csv.Flush()
+
+		if err := w.csv.Error(); err != nil {
+			return fmt.Errorf("ERROR: Could not write CSV, err='%w'", err)
+		}
+	}
+
+	if err := w.out.Flush(); err != nil {
+		return fmt.Errorf("ERROR: Could not flush export, err='%w'", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+var testUsers = []jcapi.JCUser{
+	{
+		Id: "1", UserName: "jdoe", Email: "jdoe@example.com", Sudo: true,
+		PasswordExpirationDate: jcapi.NewTimestamp(time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)),
+		Attributes:             []jcapi.JCUserAttribute{{Name: "costCenter", Value: "42"}},
+		Tags:                   []jcapi.JCTag{{Name: "ops"}, {Name: "vpn"}},
+	},
+	{Id: "2", UserName: "asmith", Email: "asmith@example.com", FirstName: "Anna \"Annie\"\tSmith"},
+}
+
+func exportUsers(t *testing.T, format Format, names ...string) string {
+	columns, err := UserColumns(names...)
+	if err != nil {
+		t.Fatalf("UserColumns() failed, err='%s'", err)
+	}
+
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer, format, columns)
+
+	for _, user := range testUsers {
+		if err := w.Write(user); err != nil {
+			t.Fatalf("Write() failed, err='%s'", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() failed, err='%s'", err)
+	}
+
+	return buffer.String()
+}
+
+func TestWriterFormats(t *testing.T) {
+	names := []string{"username", "firstname", "sudo", "password_expiration_date", "attributes.costCenter", TagNamesColumn}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{CSV, "username,firstname,sudo,password_expiration_date,attributes.costCenter,tagNames\n" +
+			"jdoe,,true,2016-05-04T12:00:00Z,42,ops;vpn\n" +
+			"asmith,\"Anna \"\"Annie\"\"\tSmith\",false,,,\n"},
+		{TSV, "username\tfirstname\tsudo\tpassword_expiration_date\tattributes.costCenter\ttagNames\n" +
+			"jdoe\t\ttrue\t2016-05-04T12:00:00Z\t42\tops;vpn\n" +
+			"asmith\tAnna \"Annie\" Smith\tfalse\t\t\t\n"},
+		{JSONLines, `{"username":"jdoe","firstname":"","sudo":"true","password_expiration_date":"2016-05-04T12:00:00Z","attributes.costCenter":"42","tagNames":"ops;vpn"}` + "\n" +
+			`{"username":"asmith","firstname":"Anna \"Annie\"\tSmith","sudo":"false","password_expiration_date":"","attributes.costCenter":"","tagNames":""}` + "\n"},
+		{YAML, "- username: \"jdoe\"\n  firstname: \"\"\n  sudo: \"true\"\n  password_expiration_date: \"2016-05-04T12:00:00Z\"\n  attributes.costCenter: \"42\"\n  tagNames: \"ops;vpn\"\n" +
+			"- username: \"asmith\"\n  firstname: \"Anna \\\"Annie\\\"\\tSmith\"\n  sudo: \"false\"\n  password_expiration_date: \"\"\n  attributes.costCenter: \"\"\n  tagNames: \"\"\n"},
+	}
+
+	for _, test := range tests {
+		if s := exportUsers(t, test.format, names...); s != test.expected {
+			t.Errorf("Unexpected %s export:\n%s\nwant:\n%s", test.format, s, test.expected)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	if s := exportUsers(t, CSV, "attributes", "_id"); s != "attributes,_id\ncostCenter=42,1\n,2\n" {
+		t.Errorf("Unexpected export:\n%s", s)
+	}
+
+	for _, names := range [][]string{{"shoe_size"}, {"attributes.cost center"}, {"Tags"}} {
+		if _, err := UserColumns(names...); err == nil {
+			t.Errorf("Expected an error for columns %v", names)
+		}
+	}
+
+	if _, err := TagColumns(TagNamesColumn); err == nil {
+		t.Errorf("Expected an error for the tag names of a tag")
+	}
+
+	columns, _ := SystemColumns("hostname")
+	columns = append(columns, NewColumn("Seen", func(record interface{}) string {
+		return record.(jcapi.JCSystem).LastContact.String()[:4]
+	}), columns[0].As("HOST"))
+
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer, CSV, columns)
+
+	lastContact, _ := jcapi.ParseTimestamp("2016-05-04")
+
+	if err := w.Write(&jcapi.JCSystem{Hostname: "web1", LastContact: lastContact}); err != nil {
+		t.Fatalf("Write() failed, err='%s'", err)
+	}
+
+	if err := w.Write(testUsers[0]); err == nil {
+		t.Fatalf("Expected an error writing a user with system columns")
+	}
+
+	w.Flush()
+
+	if s := buffer.String(); s != "hostname,Seen,HOST\nweb1,2016,web1\n" {
+		t.Errorf("Unexpected export:\n%s", s)
+	}
+
+	buffer.Reset()
+	NewWriter(&buffer, CSV, columns).Flush()
+
+	if s := buffer.String(); s != "hostname,Seen,HOST\n" {
+		t.Errorf("Expected only a header for no records, got:\n%s", s)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"CSV": CSV, "tsv": TSV, "json": JSONLines, "jsonl": JSONLines, "yml": YAML} {
+		if format, err := ParseFormat(name); err != nil || format != expected {
+			t.Errorf("ParseFormat('%s') returned %s, err='%v'", name, format, err)
+		}
+	}
+
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Errorf("Expected an error for xlsx")
+	}
+}
+
+func TestUsers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []interface{}
+
+		switch r.URL.Path {
+		case "/systemusers":
+			results = []interface{}{
+				map[string]interface{}{"_id": "1", "username": "jdoe"},
+				map[string]interface{}{"_id": "2", "username": "asmith"},
+			}
+		case "/tags":
+			results = []interface{}{
+				map[string]interface{}{"_id": "t1", "name": "ops", "systemusers": []string{"2"}},
+				map[string]interface{}{"_id": "t2", "name": "vpn", "systemusers": []string{"1", "2"}},
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalCount": len(results), "results": results})
+	}))
+	defer ts.Close()
+
+	columns, _ := UserColumns("username", TagNamesColumn)
+
+	var buffer bytes.Buffer
+
+	err := Users(context.Background(), jcapi.NewJCAPI("key", ts.URL), NewWriter(&buffer, TSV, columns))
+	if err != nil {
+		t.Fatalf("Users() failed, err='%s'", err)
+	}
+
+	if s := buffer.String(); s != "username\ttagNames\njdoe\tvpn\nasmith\tops;vpn\n" {
+		t.Errorf("Unexpected export:\n%s", s)
+	}
+}
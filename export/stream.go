@@ -0,0 +1,78 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+//
+// Users writes every system user to w as they are fetched, sorted by username, and then
+// flushes w. The users are the records of the list endpoint (see
+// jcapi.JCAPI.StreamSystemUsers()). All tags are read first when a column needs them.
+//
+func Users(ctx context.Context, jc jcapi.JCAPI, w *Writer) error {
+	tags, err := tagsFor(ctx, jc, w)
+	if err != nil {
+		return err
+	}
+
+	err = jc.StreamSystemUsers(ctx, jcapi.PageOptions{}, func(user jcapi.JCUser) error {
+		user.AddJCTags(tags)
+		return w.Write(user)
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not export system users, err='%w'", err)
+	}
+
+	return w.Flush()
+}
+
+// Systems writes every system to w as they are fetched, sorted by hostname, and then flushes w.
+func Systems(ctx context.Context, jc jcapi.JCAPI, w *Writer) error {
+	tags, err := tagsFor(ctx, jc, w)
+	if err != nil {
+		return err
+	}
+
+	err = jc.StreamSystems(ctx, jcapi.PageOptions{}, func(system jcapi.JCSystem) error {
+		system.AddJCTagsToSystem(tags)
+		return w.Write(system)
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not export systems, err='%w'", err)
+	}
+
+	return w.Flush()
+}
+
+// Tags writes every tag to w, and then flushes w.
+func Tags(ctx context.Context, jc jcapi.JCAPI, w *Writer) error {
+	tags, err := jc.GetAllTagsContext(ctx)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not export tags, err='%w'", err)
+	}
+
+	for _, tag := range tags {
+		if err := w.Write(tag); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// tagsFor returns all the tags when a column of w needs them, and none otherwise
+func tagsFor(ctx context.Context, jc jcapi.JCAPI, w *Writer) ([]jcapi.JCTag, error) {
+	if !w.needsTags() {
+		return nil, nil
+	}
+
+	tags, err := jc.GetAllTagsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not read tags, err='%w'", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,398 @@
+package jcapi
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The longest name JumpCloud accepts for a user attribute
+	MaxAttributeNameLength int = 32
+
+	// The format of the values of AttributeDate attributes
+	AttributeDateLayout string = "2006-01-02"
+)
+
+// ErrNoAttribute is returned by the typed attribute getters for an attribute the user doesn't have
+var ErrNoAttribute = errors.New("jcapi: no such attribute")
+
+var attributeNamePattern = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// The layouts, besides AttributeDateLayout, that dates are accepted in
+var attributeDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006/01/02", "01/02/2006"}
+
+// AttributeType is the type of the value of a user attribute in an AttributeSchema
+type AttributeType int
+
+const (
+	AttributeString AttributeType = iota
+	AttributeInt
+	AttributeDate // stored in the AttributeDateLayout format
+	AttributeEnum // one of AttributeSpec.Values
+)
+
+func (t AttributeType) String() string {
+	switch t {
+	case AttributeString:
+		return "string"
+	case AttributeInt:
+		return "int"
+	case AttributeDate:
+		return "date"
+	case AttributeEnum:
+		return "enum"
+	}
+
+	return fmt.Sprintf("AttributeType(%d)", int(t))
+}
+
+// AttributeSpec describes a user attribute of an AttributeSchema
+type AttributeSpec struct {
+	Name     string
+	Type     AttributeType
+	Required bool     // the attribute must be set, and not empty
+	Values   []string // the values allowed for an AttributeEnum, compared regardless of case
+}
+
+//
+// AttributeSchema declares the attributes expected on users, to check and normalize
+// JCUser.Attributes with Validate().
+//
+type AttributeSchema struct {
+	specs        []AttributeSpec
+	byName       map[string]int
+	AllowUnknown bool // accept attributes that are not in the schema, as they are
+}
+
+// AttributeViolation is an attribute that doesn't match its AttributeSchema
+type AttributeViolation struct {
+	Name    string
+	Message string
+}
+
+// AttributeSchemaError lists every attribute that doesn't match an AttributeSchema
+type AttributeSchemaError struct {
+	Violations []AttributeViolation
+}
+
+func (e *AttributeSchemaError) Error() string {
+	messages := make([]string, len(e.Violations))
+
+	for idx, violation := range e.Violations {
+		messages[idx] = fmt.Sprintf("'%s' %s", violation.Name, violation.Message)
+	}
+
+	return fmt.Sprintf("ERROR: Attributes don't match the schema: %s", strings.Join(messages, "; "))
+}
+
+//
+// ValidateAttributeName checks that name is accepted by JumpCloud as the name of a user
+// attribute: alphanumeric, and no longer than MaxAttributeNameLength.
+//
+func ValidateAttributeName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("ERROR: Attribute name is empty")
+	case len(name) > MaxAttributeNameLength:
+		return fmt.Errorf("ERROR: Attribute name exceeds %d characters [%s]", MaxAttributeNameLength, name)
+	case !attributeNamePattern.MatchString(name):
+		return fmt.Errorf("ERROR: Attribute name contains non-alphanumeric characters or spaces [%s]", name)
+	}
+
+	return nil
+}
+
+// NewAttributeSchema builds a schema from specs, checking their names.
+func NewAttributeSchema(specs ...AttributeSpec) (*AttributeSchema, error) {
+	schema := &AttributeSchema{byName: make(map[string]int)}
+
+	for _, spec := range specs {
+		if err := ValidateAttributeName(spec.Name); err != nil {
+			return nil, err
+		}
+
+		if _, ok := schema.byName[spec.Name]; ok {
+			return nil, fmt.Errorf("ERROR: Attribute '%s' is declared twice", spec.Name)
+		}
+
+		if spec.Type == AttributeEnum && len(spec.Values) == 0 {
+			return nil, fmt.Errorf("ERROR: Enum attribute '%s' has no values", spec.Name)
+		}
+
+		schema.byName[spec.Name] = len(schema.specs)
+		schema.specs = append(schema.specs, spec)
+	}
+
+	return schema, nil
+}
+
+// Spec returns the spec of the named attribute.
+func (s *AttributeSchema) Spec(name string) (spec AttributeSpec, ok bool) {
+	idx, ok := s.byName[name]
+	if ok {
+		spec = s.specs[idx]
+	}
+
+	return
+}
+
+// coerce returns the value in its canonical form for the spec
+func (spec AttributeSpec) coerce(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+
+	switch spec.Type {
+	case AttributeInt:
+		n, err := strconv.Atoi(trimmed)
+		if err != nil {
+			return value, fmt.Errorf("is not an integer: '%s'", value)
+		}
+
+		return strconv.Itoa(n), nil
+	case AttributeDate:
+		date, err := parseAttributeDate(trimmed)
+		if err != nil {
+			return value, fmt.Errorf("is not a date: '%s'", value)
+		}
+
+		return date.Format(AttributeDateLayout), nil
+	case AttributeEnum:
+		for _, allowed := range spec.Values {
+			if strings.EqualFold(trimmed, allowed) {
+				return allowed, nil
+			}
+		}
+
+		return value, fmt.Errorf("is not one of %s: '%s'", strings.Join(spec.Values, ", "), value)
+	}
+
+	return value, nil
+}
+
+func parseAttributeDate(value string) (date time.Time, err error) {
+	for _, layout := range append([]string{AttributeDateLayout}, attributeDateLayouts...) {
+		date, err = time.Parse(layout, value)
+		if err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Validate checks attributes against the schema, and returns them with their values
+// coerced to the canonical form of their type (e.g. "007" to "7" for an int, or
+// "2016/05/04" to "2016-05-04" for a date). It returns an *AttributeSchemaError that
+// lists every attribute that is unknown, repeated, missing or of the wrong type.
+// Empty values are left alone, unless the attribute is required.
+//
+func (s *AttributeSchema) Validate(attributes []JCUserAttribute) ([]JCUserAttribute, error) {
+	var violations []AttributeViolation
+
+	violate := func(name, format string, args ...interface{}) {
+		violations = append(violations, AttributeViolation{Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	coerced := make([]JCUserAttribute, len(attributes))
+	seen := make(map[string]bool)
+
+	for idx, attribute := range attributes {
+		coerced[idx] = attribute
+
+		if seen[attribute.Name] {
+			violate(attribute.Name, "is set more than once")
+			continue
+		}
+
+		seen[attribute.Name] = true
+
+		spec, ok := s.Spec(attribute.Name)
+		if !ok {
+			if !s.AllowUnknown {
+				violate(attribute.Name, "is not in the schema")
+			}
+			continue
+		}
+
+		if strings.TrimSpace(attribute.Value) == "" {
+			continue
+		}
+
+		value, err := spec.coerce(attribute.Value)
+		if err != nil {
+			violate(attribute.Name, "%s", err)
+			continue
+		}
+
+		coerced[idx].Value = value
+	}
+
+	for _, spec := range s.specs {
+		if !spec.Required {
+			continue
+		}
+
+		var value string
+		for _, attribute := range coerced {
+			if attribute.Name == spec.Name {
+				value = attribute.Value
+				break
+			}
+		}
+
+		if strings.TrimSpace(value) == "" {
+			violate(spec.Name, "is required")
+		}
+	}
+
+	if len(violations) > 0 {
+		return coerced, &AttributeSchemaError{Violations: violations}
+	}
+
+	return coerced, nil
+}
+
+// ValidateUser checks the attributes of the user, coercing them in place when they match.
+func (s *AttributeSchema) ValidateUser(user *JCUser) error {
+	attributes, err := s.Validate(user.Attributes)
+	if err != nil {
+		return err
+	}
+
+	user.Attributes = attributes
+
+	return nil
+}
+
+//
+// Typed getters and setters for the attributes of a user
+//
+
+// Attribute returns the value of the named attribute of the user.
+func (jcuser JCUser) Attribute(name string) (value string, ok bool) {
+	for _, attribute := range jcuser.Attributes {
+		if attribute.Name == name {
+			return attribute.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// AttributeInt returns the value of the named attribute as an integer, or ErrNoAttribute.
+func (jcuser JCUser) AttributeInt(name string) (int, error) {
+	value, ok := jcuser.Attribute(name)
+	if !ok {
+		return 0, fmt.Errorf("ERROR: User '%s' has no attribute '%s', err='%w'", jcuser.UserName, name, ErrNoAttribute)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("ERROR: Attribute '%s' of user '%s' is not an integer, err='%w'", name, jcuser.UserName, err)
+	}
+
+	return n, nil
+}
+
+// AttributeDate returns the value of the named attribute as a date, or ErrNoAttribute.
+func (jcuser JCUser) AttributeDate(name string) (time.Time, error) {
+	value, ok := jcuser.Attribute(name)
+	if !ok {
+		return time.Time{}, fmt.Errorf("ERROR: User '%s' has no attribute '%s', err='%w'", jcuser.UserName, name, ErrNoAttribute)
+	}
+
+	date, err := parseAttributeDate(strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ERROR: Attribute '%s' of user '%s' is not a date, err='%w'", name, jcuser.UserName, err)
+	}
+
+	return date, nil
+}
+
+// SetAttribute sets the named attribute of the user, adding it if needed.
+func (jcuser *JCUser) SetAttribute(name, value string) {
+	jcuser.Attributes = MergeAttributes(jcuser.Attributes, AttributeMerge{Set: []JCUserAttribute{{name, value}}})
+}
+
+// SetAttributeInt sets the named attribute of the user to n.
+func (jcuser *JCUser) SetAttributeInt(name string, n int) {
+	jcuser.SetAttribute(name, strconv.Itoa(n))
+}
+
+// SetAttributeDate sets the named attribute of the user to the date of t, in the AttributeDateLayout format.
+func (jcuser *JCUser) SetAttributeDate(name string, t time.Time) {
+	jcuser.SetAttribute(name, t.Format(AttributeDateLayout))
+}
+
+// DeleteAttribute removes the named attribute from the user.
+func (jcuser *JCUser) DeleteAttribute(name string) {
+	jcuser.Attributes = MergeAttributes(jcuser.Attributes, AttributeMerge{Delete: []string{name}})
+}
+
+//
+// AttributeMerge describes changes to the attributes of a user, for MergeAttributes()
+//
+type AttributeMerge struct {
+	Set    []JCUserAttribute // added, or overwriting the attribute of the same name
+	Delete []string          // the names of the attributes to remove
+
+	KeepExisting bool // only add the attributes of Set the user doesn't have, never overwrite
+	EmptyDeletes bool // an attribute of Set with an empty value removes the attribute instead
+}
+
+//
+// MergeAttributes returns the attributes of current with the changes of merge applied.
+// The attributes kept stay in the same order, without duplicates, and the ones added
+// follow in the order of merge.Set. Delete wins over Set. current is left untouched.
+//
+func MergeAttributes(current []JCUserAttribute, merge AttributeMerge) []JCUserAttribute {
+	deleted := make(map[string]bool)
+	for _, name := range merge.Delete {
+		deleted[name] = true
+	}
+
+	set := make(map[string]string)
+	var added []string
+
+	for _, attribute := range merge.Set {
+		if merge.EmptyDeletes && attribute.Value == "" {
+			deleted[attribute.Name] = true
+			continue
+		}
+
+		if _, ok := set[attribute.Name]; !ok {
+			added = append(added, attribute.Name)
+		}
+
+		set[attribute.Name] = attribute.Value
+	}
+
+	merged := make([]JCUserAttribute, 0, len(current)+len(added))
+	existing := make(map[string]bool)
+
+	for _, attribute := range current {
+		if deleted[attribute.Name] || existing[attribute.Name] {
+			continue
+		}
+
+		existing[attribute.Name] = true
+
+		if value, ok := set[attribute.Name]; ok && !merge.KeepExisting {
+			attribute.Value = value
+		}
+
+		merged = append(merged, attribute)
+	}
+
+	for _, name := range added {
+		if !existing[name] && !deleted[name] {
+			merged = append(merged, JCUserAttribute{Name: name, Value: set[name]})
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,150 @@
+package jcapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func attributesString(attributes []JCUserAttribute) string {
+	var pairs []string
+
+	for _, attribute := range attributes {
+		pairs = append(pairs, attribute.Name+"="+attribute.Value)
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+func TestValidateAttributeName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"costCenter":            true,
+		"a1":                    true,
+		strings.Repeat("a", 32): true,
+		strings.Repeat("a", 33): false,
+		"":                      false,
+		"cost center":           false,
+		"cost-center":           false,
+		"coût":                  false,
+	} {
+		if err := ValidateAttributeName(name); (err == nil) != valid {
+			t.Errorf("Unexpected result for '%s': err='%v'", name, err)
+		}
+	}
+}
+
+func TestAttributeSchemaValidate(t *testing.T) {
+	schema, err := NewAttributeSchema(
+		AttributeSpec{Name: "costCenter", Type: AttributeInt, Required: true},
+		AttributeSpec{Name: "startDate", Type: AttributeDate},
+		AttributeSpec{Name: "tier", Type: AttributeEnum, Values: []string{"Gold", "Silver"}},
+		AttributeSpec{Name: "team", Type: AttributeString},
+	)
+	if err != nil {
+		t.Fatalf("NewAttributeSchema() failed, err='%s'", err)
+	}
+
+	attributes, err := schema.Validate([]JCUserAttribute{
+		{"costCenter", " 0042"}, {"startDate", "2016/05/04"}, {"tier", "gold"}, {"team", " Ops "},
+	})
+	if err != nil {
+		t.Fatalf("Validate() failed, err='%s'", err)
+	}
+
+	if s := attributesString(attributes); s != "costCenter=42 startDate=2016-05-04 tier=Gold team= Ops " {
+		t.Fatalf("Unexpected attributes coerced: %s", s)
+	}
+
+	_, err = schema.Validate([]JCUserAttribute{
+		{"startDate", "yesterday"}, {"tier", "bronze"}, {"team", "a"}, {"team", "b"}, {"shoeSize", "9"}, {"costCenter", ""},
+	})
+
+	var schemaErr *AttributeSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Expected an *AttributeSchemaError, got '%v'", err)
+	}
+
+	var problems []string
+	for _, violation := range schemaErr.Violations {
+		problems = append(problems, violation.Name)
+	}
+
+	if strings.Join(problems, " ") != "startDate tier team shoeSize costCenter" {
+		t.Fatalf("Unexpected violations: %v", schemaErr.Violations)
+	}
+
+	schema.AllowUnknown = true
+
+	user := JCUser{Attributes: []JCUserAttribute{{"costCenter", "+7"}, {"shoeSize", "9"}}}
+	if err := schema.ValidateUser(&user); err != nil || attributesString(user.Attributes) != "costCenter=7 shoeSize=9" {
+		t.Fatalf("Unexpected attributes %v, err='%v'", user.Attributes, err)
+	}
+
+	for _, specs := range [][]AttributeSpec{
+		{{Name: "bad name"}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Type: AttributeEnum}},
+	} {
+		if _, err := NewAttributeSchema(specs...); err == nil {
+			t.Errorf("Expected an error for %v", specs)
+		}
+	}
+}
+
+func TestTypedAttributes(t *testing.T) {
+	user := JCUser{UserName: "jdoe", Attributes: []JCUserAttribute{{"costCenter", "42"}, {"team", "ops"}}}
+
+	user.SetAttributeInt("costCenter", 43)
+	user.SetAttributeDate("startDate", time.Date(2016, 5, 4, 23, 0, 0, 0, time.UTC))
+	user.SetAttribute("shift", "night")
+	user.DeleteAttribute("team")
+
+	if s := attributesString(user.Attributes); s != "costCenter=43 startDate=2016-05-04 shift=night" {
+		t.Fatalf("Unexpected attributes: %s", s)
+	}
+
+	if n, err := user.AttributeInt("costCenter"); err != nil || n != 43 {
+		t.Fatalf("AttributeInt() returned %d, err='%v'", n, err)
+	}
+
+	if date, err := user.AttributeDate("startDate"); err != nil || !date.Equal(time.Date(2016, 5, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("AttributeDate() returned %s, err='%v'", date, err)
+	}
+
+	if _, err := user.AttributeInt("team"); !errors.Is(err, ErrNoAttribute) {
+		t.Fatalf("Expected ErrNoAttribute, got '%v'", err)
+	}
+
+	if _, err := user.AttributeInt("shift"); err == nil || errors.Is(err, ErrNoAttribute) {
+		t.Fatalf("Expected a conversion error, got '%v'", err)
+	}
+}
+
+func TestMergeAttributes(t *testing.T) {
+	current := []JCUserAttribute{{"a", "1"}, {"b", "2"}, {"c", "3"}, {"a", "dup"}}
+
+	tests := []struct {
+		merge    AttributeMerge
+		expected string
+	}{
+		{AttributeMerge{}, "a=1 b=2 c=3"},
+		{AttributeMerge{Set: []JCUserAttribute{{"d", "4"}, {"b", "20"}, {"e", ""}}}, "a=1 b=20 c=3 d=4 e="},
+		{AttributeMerge{Set: []JCUserAttribute{{"d", "4"}, {"b", "20"}}, KeepExisting: true}, "a=1 b=2 c=3 d=4"},
+		{AttributeMerge{Set: []JCUserAttribute{{"b", ""}, {"d", ""}}, EmptyDeletes: true}, "a=1 c=3"},
+		{AttributeMerge{Set: []JCUserAttribute{{"d", "4"}, {"a", "10"}}, Delete: []string{"a", "c", "x"}}, "b=2 d=4"},
+	}
+
+	for _, test := range tests {
+		merged := MergeAttributes(current, test.merge)
+
+		if s := attributesString(merged); s != test.expected {
+			t.Errorf("Unexpected merge of %+v: got '%s', want '%s'", test.merge, s, test.expected)
+		}
+	}
+
+	if s := fmt.Sprint(current); s != "[{a 1} {b 2} {c 3} {a dup}]" {
+		t.Fatalf("MergeAttributes() changed its input: %s", s)
+	}
+}
@@ -1,7 +1,7 @@
 package jcapi
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/url"
 )
@@ -18,8 +18,8 @@ type JCCommandResult struct {
 	Id                 string     `json:"_id,omitempty"`                // unique database ID
 	Name               string     `json:"name"`                         // a title for display in the UI
 	Command            string     `json:"command"`                      // the actual command string to execute
-	RequestTime        string     `json:"requestTime,omitempty"`        // The time the command started
-	ResponseTime       string     `json:"responseTime,omitempty"`       // The time the command exited
+	RequestTime        Timestamp  `json:"requestTime,omitzero"`         // The time the command started
+	ResponseTime       Timestamp  `json:"responseTime,omitzero"`        // The time the command exited
 	Organization       string     `json:"organization,omitempty"`       // organization ID for this command (auto-populated)
 	Sudo               bool       `json:"sudo"`                         // Indicates whether the command should be run with sudo
 	System             string     `json:"system,omitempty"`             // The hostname of the system from which this result came
@@ -44,35 +44,29 @@ func (e JCCommandResult) ToString() string {
 	return fmt.Sprintf("CommandResult: %v", e)
 }
 
-func getJCCommandResultsFromResults(result []byte) (commands []JCCommandResult, err JCError) {
-	commandResultResults := JCCommandResultResults{}
-
-	err = json.Unmarshal(result, &commandResultResults)
-	if err != nil {
-		err = fmt.Errorf("Could not unmarshal result '%s', err='%s'", string(result), err.Error())
-		return
-	}
-
-	commands = commandResultResults.Results
-
-	return
+func (jc JCAPI) GetCommandResultDetailsById(id string) (commandResult JCCommandResult, err JCError) {
+	return jc.GetCommandResultDetailsByIdContext(context.Background(), id)
 }
 
-func (jc JCAPI) GetCommandResultDetailsById(id string) (commandResult JCCommandResult, err JCError) {
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(Read), COMMAND_RESULTS_PATH+"/"+id, nil)
+func (jc JCAPI) GetCommandResultDetailsByIdContext(ctx context.Context, id string) (commandResult JCCommandResult, err JCError) {
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), COMMAND_RESULTS_PATH+"/"+id, nil)
 	if err != nil {
-		err = fmt.Errorf("Could not get command result details for ID '%s', err='%s'", id, err.Error())
+		return commandResult, fmt.Errorf("ERROR: Could not get command result details for ID '%s', err='%w'", id, err)
 	}
 
-	err = json.Unmarshal(buffer, &commandResult)
+	err = decodeJSON(buffer, &commandResult)
 	if err != nil {
-		err = fmt.Errorf("Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
+		return commandResult, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 	}
 
 	return
 }
 
 func (jc JCAPI) GetCommandResultsByName(name string) (commandResultList []JCCommandResult, err JCError) {
+	return jc.GetCommandResultsByNameContext(context.Background(), name)
+}
+
+func (jc JCAPI) GetCommandResultsByNameContext(ctx context.Context, name string) (commandResultList []JCCommandResult, err JCError) {
 	searchString1 := "search[fields][]"
 	searchString2 := "=name" // can't escape the = here, or we'll get a failure
 	searchString3 := "search[searchTerm]"
@@ -81,39 +75,43 @@ func (jc JCAPI) GetCommandResultsByName(name string) (commandResultList []JCComm
 		return nil, fmt.Errorf("ERROR: Name is a required search field and cannot be \"\"")
 	}
 
-	for skip := 0; skip == 0 || len(commandResultList) == searchLimit; skip += searchSkipInterval {
-		urlQuery := fmt.Sprintf("%s?skip=%d&limit=%d&sort=-requestTime&%s%s&%s=%s", COMMAND_RESULTS_PATH, skip, searchLimit,
-			url.QueryEscape(searchString1), searchString2, url.QueryEscape(searchString3), url.QueryEscape(name))
+	urlQuery := fmt.Sprintf("%s?%s%s&%s=%s", COMMAND_RESULTS_PATH,
+		url.QueryEscape(searchString1), searchString2, url.QueryEscape(searchString3), url.QueryEscape(name))
 
-		buffer, err2 := jc.DoBytes(MapJCOpToHTTP(Read), urlQuery, nil)
-		if err2 != nil {
-			return nil, fmt.Errorf("ERROR: Get CommandResults to JumpCloud failed, err='%s'", err2)
-		}
+	pager := jc.NewPager(ctx, urlQuery, PageOptions{Sort: "-requestTime"})
+
+	for pager.Next() {
+		var result JCCommandResult
 
-		resultsBlock, err2 := getJCCommandResultsFromResults(buffer)
-		if err2 != nil {
-			err = fmt.Errorf("Could not get resultsBlock data, err='%s'", err2.Error())
-			return
+		err = pager.Decode(&result)
+		if err != nil {
+			return nil, err
 		}
 
-		for _, result := range resultsBlock {
-			if result.Id != "" {
-				commandResultList = append(commandResultList, result)
-			}
+		if result.Id != "" {
+			commandResultList = append(commandResultList, result)
 		}
 	}
 
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Get CommandResults to JumpCloud failed, err='%w'", pager.Err())
+	}
+
 	return
 }
 
 func (jc JCAPI) GetCommandResultsBySavedCommandID(id string) (commandResults []JCCommandResult, err JCError) {
+	return jc.GetCommandResultsBySavedCommandIDContext(context.Background(), id)
+}
+
+func (jc JCAPI) GetCommandResultsBySavedCommandIDContext(ctx context.Context, id string) (commandResults []JCCommandResult, err JCError) {
 	url := fmt.Sprintf("%s/%s/results", COMMAND_PATH, id)
-	body, err := jc.DoBytes(MapJCOpToHTTP(Read), url, nil)
+	body, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(body, &commandResults); err != nil {
+	if err := decodeJSON(body, &commandResults); err != nil {
 		return nil, err
 	}
 	return commandResults, err
@@ -139,11 +137,15 @@ func GetInterfaceArrayFromJCCommandResults(commandResults []JCCommandResult) (in
 }
 
 func (jc JCAPI) DeleteCommandResult(id string) (err JCError) {
+	return jc.DeleteCommandResultContext(context.Background(), id)
+}
+
+func (jc JCAPI) DeleteCommandResultContext(ctx context.Context, id string) (err JCError) {
 	url := fmt.Sprintf("%s/%s", COMMAND_RESULTS_PATH, id)
 
-	_, err2 := jc.DoBytes(MapJCOpToHTTP(Delete), url, nil)
+	_, err2 := jc.DoBytesContext(ctx, MapJCOpToHTTP(Delete), url, nil)
 	if err2 != nil {
-		return fmt.Errorf("ERROR: DELETE CommandResults failed, err='%s'", err2)
+		return fmt.Errorf("ERROR: DELETE CommandResults failed, err='%w'", err2)
 	}
 
 	return
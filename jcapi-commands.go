@@ -1,6 +1,7 @@
 package jcapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -45,46 +46,28 @@ func (e JCCommand) ToString() string {
 	return fmt.Sprintf("command: %v", e)
 }
 
-func getJCCommandsFromResults(result []byte) (commands []JCCommand, err JCError) {
-	commandResults := JCCommandResults{}
-
-	err = json.Unmarshal(result, &commandResults)
-	if err != nil {
-		err = fmt.Errorf("Could not unmarshal result '%s', err='%s'", string(result), err.Error())
-	}
-
-	commands = commandResults.Results
-
-	return
-}
-
 func (jc JCAPI) GetAllCommands() (commandList []JCCommand, err JCError) {
+	return jc.GetAllCommandsContext(context.Background())
+}
 
-	for skip := 0; skip == 0 || len(commandList) == searchLimit; skip += searchSkipInterval {
-		url := fmt.Sprintf("%s?sort=hostname&skip=%d&limit=%d", COMMAND_PATH, skip, searchLimit)
-
-		jcSysRec, err2 := jc.DoBytes(MapJCOpToHTTP(Read), url, nil)
-
-		if err2 != nil {
-			return nil, fmt.Errorf("ERROR: Get commands to JumpCloud failed, err='%s'", err2)
-		}
+func (jc JCAPI) GetAllCommandsContext(ctx context.Context) (commandList []JCCommand, err JCError) {
+	pager := jc.NewPager(ctx, COMMAND_PATH, PageOptions{Sort: "name"})
 
-		if jcSysRec == nil {
-			return nil, fmt.Errorf("ERROR: No commands found")
-		}
+	for pager.Next() {
+		var command JCCommand
 
-		resultsBlock, err2 := getJCCommandsFromResults(jcSysRec)
-		if err2 != nil {
-			err = fmt.Errorf("Could not get resultsBlock data, err='%s'", err2.Error())
-			return
+		err = pager.Decode(&command)
+		if err != nil {
+			return nil, err
 		}
 
-		for i, _ := range resultsBlock {
-			if resultsBlock[i].Id != "" {
-				commandList = append(commandList, resultsBlock[i])
-			}
+		if command.Id != "" {
+			commandList = append(commandList, command)
 		}
+	}
 
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Get commands to JumpCloud failed, err='%w'", pager.Err())
 	}
 
 	return
@@ -118,15 +101,23 @@ func GetInterfaceArrayFromJCCommand(commands []JCCommand) (interfaceArray []inte
 // for most use cases.
 //
 func (jc JCAPI) AddUpdateCommand(op JCOp, command JCCommand) (commandResult JCCommand, err JCError) {
-	commandResult, err = jc.HandleCommand(COMMAND_PATH, op, command)
+	return jc.AddUpdateCommandContext(context.Background(), op, command)
+}
+
+func (jc JCAPI) AddUpdateCommandContext(ctx context.Context, op JCOp, command JCCommand) (commandResult JCCommand, err JCError) {
+	commandResult, err = jc.HandleCommandContext(ctx, COMMAND_PATH, op, command)
 
 	return
 }
 
 func (jc JCAPI) HandleCommand(path string, op JCOp, command JCCommand) (commandResult JCCommand, err JCError) {
+	return jc.HandleCommandContext(context.Background(), path, op, command)
+}
+
+func (jc JCAPI) HandleCommandContext(ctx context.Context, path string, op JCOp, command JCCommand) (commandResult JCCommand, err JCError) {
 	data, err := json.Marshal(command)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not marshal JCCommand object, err='%s'", err.Error())
+		err = fmt.Errorf("ERROR: Could not marshal JCCommand object, err='%w'", err)
 		return
 	}
 
@@ -135,17 +126,17 @@ func (jc JCAPI) HandleCommand(path string, op JCOp, command JCCommand) (commandR
 		url += "/" + command.Id
 	}
 
-	result, err := jc.DoBytes(MapJCOpToHTTP(op), url, data)
+	result, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(op), url, data)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not '%s' new JCCommand object, err='%s'", MapJCOpToHTTP(op), err.Error())
+		err = fmt.Errorf("ERROR: Could not '%s' new JCCommand object, err='%w'", MapJCOpToHTTP(op), err)
 		return
 	}
 
 	commandResult = JCCommand{}
 
-	err = json.Unmarshal(result, &commandResult)
+	err = decodeJSON(result, &commandResult)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not unmarshal result '%s', err='%s'", string(result), err.Error())
+		err = fmt.Errorf("ERROR: Could not unmarshal result '%s', err='%w'", string(result), err)
 		return
 	}
 
@@ -153,16 +144,24 @@ func (jc JCAPI) HandleCommand(path string, op JCOp, command JCCommand) (commandR
 }
 
 func (jc JCAPI) DeleteCommand(command JCCommand) JCError {
-	_, err := jc.Delete(fmt.Sprintf("/%s/%s", COMMAND_PATH, command.Id))
+	return jc.DeleteCommandContext(context.Background(), command)
+}
+
+func (jc JCAPI) DeleteCommandContext(ctx context.Context, command JCCommand) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("/%s/%s", COMMAND_PATH, command.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete command ID '%s': err='%s'", command.Id, err.Error())
+		return fmt.Errorf("ERROR: Could not delete command ID '%s': err='%w'", command.Id, err)
 	}
 
 	return nil
 }
 
 func (jc JCAPI) RunCommand(command JCCommand) JCError {
-	_, err := jc.HandleCommand(RUN_COMMAND_PATH, Insert, command)
+	return jc.RunCommandContext(context.Background(), command)
+}
+
+func (jc JCAPI) RunCommandContext(ctx context.Context, command JCCommand) JCError {
+	_, err := jc.HandleCommandContext(ctx, RUN_COMMAND_PATH, Insert, command)
 
 	return err
 }
@@ -0,0 +1,67 @@
+package jcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetSystemUsersContextCancelledBetweenPages(t *testing.T) {
+	var listCalls int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/systemusers" {
+			atomic.AddInt32(&listCalls, 1)
+
+			// Return a full page so the caller believes there is another one to fetch
+			var results []string
+			for i := 0; i < searchLimit; i++ {
+				results = append(results, fmt.Sprintf(`{"_id":"u%d","username":"user%d","email":"user%d@example.com","sudo":false}`, i, i, i))
+			}
+			fmt.Fprintf(w, `{"totalCount":%d,"results":[%s]}`, 2*searchLimit, strings.Join(results, ","))
+
+			// The whole list is read before any details are fetched, give up after the first page
+			cancel()
+			return
+		}
+
+		t.Errorf("No details should be fetched once the context is cancelled, got '%s'", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	_, err := jc.GetSystemUsersContext(ctx, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got err='%v'", err)
+	}
+
+	if calls := atomic.LoadInt32(&listCalls); calls != 1 {
+		t.Fatalf("Expected the crawl to stop after 1 page, but %d pages were requested", calls)
+	}
+}
+
+func TestDoBytesContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("Request should never have been sent with a cancelled context")
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	_, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), SYSTEMS_PATH, nil)
+	if err == nil {
+		t.Fatalf("Expected an error from a cancelled context")
+	}
+}
@@ -0,0 +1,249 @@
+package jcapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// How much of the payload, on either side of the failure, goes into a DecodeError
+	decodeExcerptSize = 64
+)
+
+//
+// DecodeError describes a JumpCloud payload that could not be decoded into the type
+// we expected. Every call in this package returns one (wrapped, use errors.As() to get
+// at it) instead of panicking on an unexpected payload.
+//
+type DecodeError struct {
+	Type    string // the Go type we were decoding into
+	Path    string // JSON path of the failure, e.g. $.results[3].unix_uid
+	Offset  int64  // offset of the failure in the payload, -1 when unknown
+	Excerpt string // the part of the payload around the failure
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("ERROR: Could not decode %s at %s, err='%s', payload='%s'", e.Type, e.Path, e.Err, e.Excerpt)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeJSON unmarshals data into v, returning a *DecodeError on failure
+func decodeJSON(data []byte, v interface{}) error {
+	return decodeJSONAt(data, "$", v)
+}
+
+//
+// decodeJSONAt works like decodeJSON(), for data found at path root of a larger
+// payload (such as one item of a results array).
+//
+func decodeJSONAt(data []byte, root string, v interface{}) error {
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+
+	return newDecodeError(data, root, v, err)
+}
+
+func newDecodeError(data []byte, root string, v interface{}, err error) *DecodeError {
+	offset := int64(-1)
+	field := ""
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		offset = typeErr.Offset
+		field = typeErr.Field
+	}
+
+	if offset > int64(len(data)) {
+		offset = -1
+	}
+
+	typeName := "<nil>"
+	if t := reflect.TypeOf(v); t != nil {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		typeName = t.String()
+	}
+
+	return &DecodeError{
+		Type:    typeName,
+		Path:    root + jsonPath(data, offset, field),
+		Offset:  offset,
+		Excerpt: excerpt(data, offset),
+		Err:     err,
+	}
+}
+
+func excerpt(data []byte, offset int64) string {
+	start, end := int64(0), int64(len(data))
+
+	if offset < 0 {
+		if end > 2*decodeExcerptSize {
+			end = 2 * decodeExcerptSize
+		}
+	} else {
+		if offset > decodeExcerptSize {
+			start = offset - decodeExcerptSize
+		}
+		if offset+decodeExcerptSize < end {
+			end = offset + decodeExcerptSize
+		}
+	}
+
+	return string(data[start:end])
+}
+
+//
+// jsonPath returns the path, relative to the root of data, of the value found at offset.
+//
+// Offsets reported for values decoded by a custom UnmarshalJSON() are relative to that
+// value rather than to data, so the path found is only used when it agrees with field
+// (the dotted path encoding/json reports for type errors). Otherwise we fall back on
+// field, which only has the array indexes with some versions of encoding/json.
+//
+func jsonPath(data []byte, offset int64, field string) string {
+	if offset >= 0 {
+		path, keys := walkJSONPath(data, offset)
+		if field == "" || pathKeys(field) == keys || strings.HasSuffix(keys, "."+pathKeys(field)) {
+			return path
+		}
+	}
+
+	path := ""
+
+	for _, name := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(name); err == nil {
+			path += "[" + name + "]"
+		} else if name != "" {
+			path += formatPathKey(name)
+		}
+	}
+
+	return path
+}
+
+// pathKeys drops the array indexes from a dotted field path
+func pathKeys(field string) string {
+	var keys []string
+
+	for _, name := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(name); err != nil {
+			keys = append(keys, name)
+		}
+	}
+
+	return strings.Join(keys, ".")
+}
+
+//
+// unmarshalField decodes the value of the named field with a custom UnmarshalJSON(),
+// and names the field in the error, as encoding/json doesn't always do it for us.
+//
+func unmarshalField(name string, data json.RawMessage, v json.Unmarshaler) error {
+	if data == nil {
+		return nil
+	}
+
+	err := v.UnmarshalJSON(data)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			name += "." + typeErr.Field
+		}
+		typeErr.Field = name
+	}
+
+	return err
+}
+
+type jsonFrame struct {
+	array     bool
+	index     int    // index of the current array element
+	key       string // key of the current object member
+	expectKey bool
+}
+
+// walkJSONPath tokenizes data up to offset, or up to the first syntax error
+func walkJSONPath(data []byte, offset int64) (path, keys string) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var stack []jsonFrame
+
+	for decoder.InputOffset() < offset {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		top := len(stack) - 1
+
+		switch {
+		case token == json.Delim('}') || token == json.Delim(']'):
+			stack = stack[:top]
+			if top > 0 && !stack[top-1].array {
+				stack[top-1].expectKey = true
+			}
+
+		case top >= 0 && stack[top].expectKey:
+			stack[top].key, _ = token.(string)
+			stack[top].expectKey = false
+
+		default:
+			if top >= 0 && stack[top].array {
+				stack[top].index++
+			}
+
+			switch token {
+			case json.Delim('{'):
+				stack = append(stack, jsonFrame{expectKey: true})
+			case json.Delim('['):
+				stack = append(stack, jsonFrame{array: true, index: -1})
+			default:
+				if top >= 0 && !stack[top].array {
+					stack[top].expectKey = true
+				}
+			}
+		}
+	}
+
+	var keyList []string
+
+	for _, frame := range stack {
+		if frame.array {
+			if frame.index >= 0 {
+				path += fmt.Sprintf("[%d]", frame.index)
+			}
+		} else if frame.key != "" {
+			path += formatPathKey(frame.key)
+			keyList = append(keyList, frame.key)
+		}
+	}
+
+	return path, strings.Join(keyList, ".")
+}
+
+func formatPathKey(key string) string {
+	for _, c := range key {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return fmt.Sprintf("[%q]", key)
+		}
+	}
+
+	return "." + key
+}
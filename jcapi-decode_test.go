@@ -0,0 +1,217 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeErrorPath(t *testing.T) {
+	tests := []struct {
+		payload string
+		v       interface{}
+		path    string
+	}{
+		{`{"results":[{"_id":"a"},{"_id":5}]}`, &JCSystemResults{}, "$.results[1]._id"},
+		{`{"results":[{"_id":"a"},{"_id":"b",}]}`, &JCSystemResults{}, "$.results[1]._id"},
+		{`{"_id":"a","networkInterfaces":[{"address":"10.0.0.1"},{"address":true}]}`, &JCSystem{}, "$.networkInterfaces[1].address"},
+		{`{"a b":{"c":1}}`, &map[string]map[string]string{}, `$["a b"].c`},
+		{`[1,2`, &[]int{}, "$[1]"},
+		{`{"_id":"a","unix_uid":{}}`, &JCUser{}, "$.unix_uid"},
+		{`{"_id":"a","attributes":[{"name":"a"},{"name":"b","value":[]}]}`, &JCUser{}, "$.attributes[1].value"},
+	}
+
+	for _, test := range tests {
+		err := decodeJSON([]byte(test.payload), test.v)
+
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("Expected a DecodeError for '%s', got '%v'", test.payload, err)
+		}
+
+		if decodeErr.Path != test.path {
+			t.Errorf("Unexpected path '%s' for '%s', expected '%s'", decodeErr.Path, test.payload, test.path)
+		}
+
+		if decodeErr.Excerpt == "" || !strings.Contains(test.payload, decodeErr.Excerpt) {
+			t.Errorf("Unexpected excerpt '%s' for '%s'", decodeErr.Excerpt, test.payload)
+		}
+	}
+}
+
+func TestDecodeErrorExcerptIsBounded(t *testing.T) {
+	payload := `{"_id":"` + strings.Repeat("x", 1000) + `","hostname":5,"other":"` + strings.Repeat("y", 1000) + `"}`
+
+	err := decodeJSON([]byte(payload), &JCSystem{})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a DecodeError, got '%v'", err)
+	}
+
+	if len(decodeErr.Excerpt) > 2*decodeExcerptSize || !strings.Contains(decodeErr.Excerpt, `"hostname":5`) {
+		t.Fatalf("Unexpected excerpt '%s'", decodeErr.Excerpt)
+	}
+
+	if decodeErr.Type != "jcapi.JCSystem" || decodeErr.Path != "$.hostname" {
+		t.Fatalf("Unexpected type '%s' or path '%s'", decodeErr.Type, decodeErr.Path)
+	}
+}
+
+func TestGetSystemUserByEmailDecodeErrorPath(t *testing.T) {
+	rt := &recordingTransport{body: `{"totalCount":2,"results":[{"_id":"a"},{"_id":"b","email":5}]}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	_, err = jc.GetSystemUserByEmail("b@example.com", false)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Path != "$.results[1].email" {
+		t.Fatalf("Expected a DecodeError at $.results[1].email, got '%v'", err)
+	}
+}
+
+func TestUnexpectedPayloadsReturnDecodeErrors(t *testing.T) {
+	for _, body := range []string{`[]`, `"user"`, `{"_id":["a"]}`, `{"_id":"a","attributes":{}}`} {
+		jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(&recordingTransport{body: body}))
+		if err != nil {
+			t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+		}
+
+		var decodeErr *DecodeError
+
+		_, err = jc.GetSystemUserById("a", false)
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("GetSystemUserById() should fail with a DecodeError on '%s', got '%v'", body, err)
+		}
+
+		_, err = jc.AddUpdateUser(Insert, JCUser{Email: "a@example.com"})
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("AddUpdateUser() should fail with a DecodeError on '%s', got '%v'", body, err)
+		}
+
+		_, err = jc.GetTagsByUrl(TAGS_PATH)
+		if body != `{"_id":"a","attributes":{}}` && !errors.As(err, &decodeErr) {
+			t.Errorf("GetTagsByUrl() should fail with a DecodeError on '%s', got '%v'", body, err)
+		}
+	}
+}
+
+//
+// The fuzz targets below run their seed corpus as part of go test. Run them with
+// -fuzz to feed them random JSON: decoding must never panic, and every failure must
+// be a DecodeError.
+//
+var fuzzSeeds = []string{
+	``,
+	`null`,
+	`[]`,
+	`{}`,
+	`"x"`,
+	`{"results":null}`,
+	`{"totalCount":"2","results":[{}]}`,
+	`{"results":[{"_id":"a","unix_uid":12,"unix_guid":"12"}],"totalCount":1}`,
+	`{"results":{"_id":"a"}}`,
+	`{"_id":"a","attributes":[{"name":1,"value":null}],"password_expiration_date":"yesterday"}`,
+	`{"_id":"a","tags":"t","systems":[1],"networkInterfaces":{}}`,
+	`{"_id":"a","mfa":{"configured":true},"unix_uid":[]}`,
+	`{"u1":null,"u2":{"attributes":{"sudo":{"enabled":"yes"}}}}`,
+	`{"results":[{"_id":"a"},`,
+	`{"é":"\ud800"}`,
+}
+
+func checkDecodeError(t *testing.T, data []byte, err error) {
+	var decodeErr *DecodeError
+
+	if err != nil && !errors.As(err, &decodeErr) {
+		t.Fatalf("Decoding '%s' failed without a DecodeError, err='%s'", data, err)
+	}
+}
+
+func FuzzDecodeSystemUsers(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var user JCUser
+		checkDecodeError(t, data, decodeJSON(data, &user))
+
+		if user.Id != "" {
+			// Whatever we managed to decode must survive a round trip
+			if _, err := json.Marshal(user); err != nil {
+				t.Fatalf("Could not marshal a decoded user, err='%s'", err)
+			}
+		}
+
+		var users JCUserResults
+		checkDecodeError(t, data, decodeJSON(data, &users))
+	})
+}
+
+func FuzzDecodeSystems(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecodeError(t, data, decodeJSON(data, &JCSystem{}))
+		checkDecodeError(t, data, decodeJSON(data, &JCSystemResults{}))
+		checkDecodeError(t, data, decodeJSON(data, &map[string]json.RawMessage{}))
+	})
+}
+
+func FuzzDecodeTags(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := getJCTagsFromResults(data)
+		checkDecodeError(t, data, err)
+	})
+}
+
+func FuzzDecodeCommands(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecodeError(t, data, decodeJSON(data, &JCCommand{}))
+		checkDecodeError(t, data, decodeJSON(data, &JCCommandResult{}))
+		checkDecodeError(t, data, decodeJSON(data, &[]JCCommandResult{}))
+	})
+}
+
+func FuzzDecodeIDSourcesAndRadiusServers(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecodeError(t, data, decodeJSON(data, &JCIDSourceResults{}))
+		checkDecodeError(t, data, decodeJSON(data, &JCIDSource{}))
+		checkDecodeError(t, data, decodeJSON(data, &JCRadiusServerResults{}))
+		checkDecodeError(t, data, decodeJSON(data, &JCRadiusServer{}))
+	})
+}
+
+func FuzzDecodeResultsStream(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, err := decodeResultsStream(strings.NewReader(string(data)), func(dec *json.Decoder, root string) error {
+			return decodeStreamItem(dec, root, &JCUser{})
+		})
+		checkDecodeError(t, data, err)
+
+		checkDecodeError(t, data, decodeJSON(data, &JCPage{}))
+	})
+}
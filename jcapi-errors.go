@@ -0,0 +1,133 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+//
+// Sentinel errors matched by errors.Is() against any error returned by this package
+// that was caused by a JumpCloud API response with the corresponding status code.
+//
+var (
+	ErrBadRequest   = errors.New("jcapi: bad request")
+	ErrUnauthorized = errors.New("jcapi: unauthorized")
+	ErrForbidden    = errors.New("jcapi: forbidden")
+	ErrNotFound     = errors.New("jcapi: not found")
+	ErrConflict     = errors.New("jcapi: conflict")
+	ErrRateLimited  = errors.New("jcapi: rate limited")
+	ErrServer       = errors.New("jcapi: server error")
+)
+
+const (
+	requestIDHeader string = "X-Request-Id"
+)
+
+// JCErrorBody is the error document JumpCloud sends back with a failed request
+type JCErrorBody struct {
+	Message string `json:"message,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Detail  string `json:"error,omitempty"`
+}
+
+//
+// APIError describes a failed call to the JumpCloud API, either because the request
+// could not be sent (Err is set and StatusCode is 0), or because JumpCloud answered
+// with an error status. Use errors.As() to get at it through the wrapping done by
+// the higher level calls, and errors.Is() to compare it with ErrNotFound and friends.
+//
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	Path       string
+	RequestID  string
+	Body       *JCErrorBody // nil when JumpCloud did not send a JSON error document
+	RawBody    []byte
+	Err        error // the underlying cause, if any
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 && e.Err != nil {
+		return fmt.Sprintf("ERROR: client.Do() failed, err='%s'", e.Err)
+	}
+
+	return fmt.Sprintf("JumpCloud HTTP response status='%s'", e.Status)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel error for the response status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// Message returns JumpCloud's explanation of the failure, when it gave one.
+func (e *APIError) Message() string {
+	if e.Body == nil {
+		return ""
+	}
+
+	if e.Body.Message != "" {
+		return e.Body.Message
+	}
+
+	return e.Body.Detail
+}
+
+func newTransportError(method, path string, err error) *APIError {
+	return &APIError{
+		Method: method,
+		Path:   path,
+		Err:    err,
+	}
+}
+
+// newResponseError builds an APIError from a failed response, consuming its body
+func newResponseError(method, path string, resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Method:     method,
+		Path:       path,
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+
+	buffer, err := ioutil.ReadAll(io.LimitReader(resp.Body, responseSize))
+	if err != nil {
+		apiErr.Err = err
+		return apiErr
+	}
+
+	apiErr.RawBody = buffer
+
+	body := JCErrorBody{}
+	if json.Unmarshal(buffer, &body) == nil && body != (JCErrorBody{}) {
+		apiErr.Body = &body
+	}
+
+	return apiErr
+}
@@ -0,0 +1,95 @@
+package jcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1234")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not found","name":"NotFoundError"}`))
+	}))
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	_, err := jc.GetSystemById("5a5a5a5a5a5a5a5a5a5a5a5a", false)
+	if err == nil {
+		t.Fatalf("Expected an error for a missing system")
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected errors.Is(err, ErrNotFound) to hold for err='%s'", err)
+	}
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrConflict) || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("A 404 should only match ErrNotFound, err='%s'", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected errors.As() to find an *APIError in err='%s'", err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Method != "GET" || apiErr.Path != SYSTEMS_PATH+"/5a5a5a5a5a5a5a5a5a5a5a5a" {
+		t.Fatalf("Unexpected APIError contents: %+v", apiErr)
+	}
+	if apiErr.RequestID != "req-1234" {
+		t.Fatalf("Expected request ID 'req-1234', got '%s'", apiErr.RequestID)
+	}
+	if apiErr.Body == nil || apiErr.Message() != "Not found" || apiErr.Body.Name != "NotFoundError" {
+		t.Fatalf("Expected the error body to be parsed, got %+v", apiErr.Body)
+	}
+
+	_, err = jc.GetCommandResultDetailsById("5a5a5a5a5a5a5a5a5a5a5a5a")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected errors.Is(err, ErrNotFound) to hold for a command result, err='%v'", err)
+	}
+
+	// Existing callers compare error strings, keep them stable
+	if apiErr.Error() != "JumpCloud HTTP response status='404 Not Found'" {
+		t.Fatalf("Unexpected error string '%s'", apiErr.Error())
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	sentinels := map[int]error{
+		http.StatusBadRequest:          ErrBadRequest,
+		http.StatusUnauthorized:        ErrUnauthorized,
+		http.StatusForbidden:           ErrForbidden,
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusConflict:            ErrConflict,
+		http.StatusTooManyRequests:     ErrRateLimited,
+		http.StatusInternalServerError: ErrServer,
+	}
+
+	for status, sentinel := range sentinels {
+		err := &APIError{StatusCode: status}
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("Status %d should match '%s'", status, sentinel)
+		}
+	}
+}
+
+func TestAPIErrorTransportCause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jc := NewJCAPI("fake-key", "http://127.0.0.1:1")
+
+	_, err := jc.GetAllTagsContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the context error to be reachable through err='%v'", err)
+	}
+
+	_, err = jc.GetTagByNameContext(ctx, "some tag")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 0 || !errors.Is(apiErr, context.Canceled) {
+		t.Fatalf("Expected a transport APIError wrapping context.Canceled, got '%v'", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !go1.24
+
+package jcapi
+
+//
+// The models leave their zero timestamps out of the JSON they are marshalled to with the
+// omitzero option of their tags, which needs Go 1.24 or later. Older versions ignore it
+// and send those timestamps as null, clearing them on JumpCloud, so they stop here.
+//
+var _ = jcapiRequiresGo1_24OrLater
@@ -1,9 +1,9 @@
 package jcapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 const (
@@ -15,15 +15,28 @@ type JCIDSourceResults struct {
 }
 
 type JCIDSource struct {
-	Id             string `json:"_id,omitempty"`
-	Name           string `json:"name"`
-	Organization   string `json:"organization,omitempty"`
-	Type           string `json:type`
-	Version        string `json:version`
-	IpAddress      string `json:ipAddress`
-	LastUpdateTime string `json:lastUpdateTime,omitempty`
-	DN             string `json:dn`
-	Active         bool   `json:active,omitempty`
+	Id             string    `json:"_id,omitempty"`
+	Name           string    `json:"name"`
+	Organization   string    `json:"organization,omitempty"`
+	Type           string    `json:"type"`
+	Version        string    `json:"version"`
+	IpAddress      string    `json:"ipAddress"`
+	LastUpdateTime Timestamp `json:"lastUpdateTime,omitzero"`
+	DN             string    `json:"dn"`
+	Active         bool      `json:"active,omitempty"`
+}
+
+// jcIDSourceJSON is the document marshalJSON() sends for a JCIDSource
+type jcIDSourceJSON struct {
+	Id             string    `json:"_id,omitempty"`
+	Name           string    `json:"name"`
+	Organization   string    `json:"organization"`
+	Type           string    `json:"type"`
+	Version        string    `json:"version"`
+	IpAddress      string    `json:"ipAddress"`
+	LastUpdateTime Timestamp `json:"lastUpdateTime,omitzero"`
+	DN             string    `json:"dn"`
+	Active         *bool     `json:"active,omitempty"` // nil leaves 'active' out of the document
 }
 
 func (e JCIDSource) ToString() string {
@@ -32,44 +45,44 @@ func (e JCIDSource) ToString() string {
 }
 
 func (e JCIDSource) marshalJSON(writeActive bool) ([]byte, error) {
-
-	var builder []string
-
-	if e.Id != "" {
-		builder = append(builder, buildJSONKeyValuePair("_id", e.Id))
+	fields := jcIDSourceJSON{
+		Id:             e.Id,
+		Name:           e.Name,
+		Organization:   e.Organization,
+		Type:           e.Type,
+		Version:        e.Version,
+		IpAddress:      e.IpAddress,
+		LastUpdateTime: e.LastUpdateTime,
+		DN:             e.DN,
 	}
 
-	builder = append(builder, buildJSONKeyValuePair("name", e.Name))
-	builder = append(builder, buildJSONKeyValuePair("organization", e.Organization))
-	builder = append(builder, buildJSONKeyValuePair("type", e.Type))
-	builder = append(builder, buildJSONKeyValuePair("version", e.Version))
-	builder = append(builder, buildJSONKeyValuePair("ipAddress", e.IpAddress))
-	builder = append(builder, buildJSONKeyValuePair("lastUpdateTime", e.LastUpdateTime))
-	builder = append(builder, buildJSONKeyValuePair("dn", e.DN))
-
 	//
 	// We never write 'active' out on a PUT, to prevent a race condition around
 	// where the the user may change the setting between when we read the
 	// object and write it back in to update the lastUpdateTime.
 	//
 	if writeActive {
-		builder = append(builder, buildJSONKeyValueBoolPair("active", e.Active))
+		fields.Active = &e.Active
 	}
 
-	return []byte("{" + strings.Join(builder, ",") + "}"), nil
+	return json.Marshal(fields)
 }
 
 func (jc JCAPI) GetAllIDSources() (idSources []JCIDSource, err JCError) {
-	result, err := jc.DoBytes(MapJCOpToHTTP(Read), IDSOURCES_PATH, nil)
+	return jc.GetAllIDSourcesContext(context.Background())
+}
+
+func (jc JCAPI) GetAllIDSourcesContext(ctx context.Context) (idSources []JCIDSource, err JCError) {
+	result, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), IDSOURCES_PATH, nil)
 	if err != nil {
-		return idSources, fmt.Errorf("ERROR: Could not list ID sources, err='%s'", err)
+		return idSources, fmt.Errorf("ERROR: Could not list ID sources, err='%w'", err)
 	}
 
 	idSourceResults := JCIDSourceResults{}
 
-	err = json.Unmarshal(result, &idSourceResults)
+	err = decodeJSON(result, &idSourceResults)
 	if err != nil {
-		err = fmt.Errorf("Could not unmarshal result set, err='%s'", err.Error())
+		err = fmt.Errorf("Could not unmarshal result set, err='%w'", err)
 		return
 	}
 
@@ -79,9 +92,13 @@ func (jc JCAPI) GetAllIDSources() (idSources []JCIDSource, err JCError) {
 }
 
 func (jc JCAPI) GetIDSourceByName(name string) (idSource JCIDSource, exists bool, err JCError) {
-	e, err := jc.GetAllIDSources()
+	return jc.GetIDSourceByNameContext(context.Background(), name)
+}
+
+func (jc JCAPI) GetIDSourceByNameContext(ctx context.Context, name string) (idSource JCIDSource, exists bool, err JCError) {
+	e, err := jc.GetAllIDSourcesContext(ctx)
 	if err != nil {
-		return idSource, false, fmt.Errorf("ERROR: Could not gather all ID source objects, err='%s'", err)
+		return idSource, false, fmt.Errorf("ERROR: Could not gather all ID source objects, err='%w'", err)
 	}
 
 	for _, idSource = range e {
@@ -98,9 +115,13 @@ func (jc JCAPI) GetIDSourceByName(name string) (idSource JCIDSource, exists bool
 // Add or Update an ID source in place on JumpCloud
 //
 func (jc JCAPI) AddUpdateIDSource(op JCOp, idSource JCIDSource) (string, JCError) {
+	return jc.AddUpdateIDSourceContext(context.Background(), op, idSource)
+}
+
+func (jc JCAPI) AddUpdateIDSourceContext(ctx context.Context, op JCOp, idSource JCIDSource) (string, JCError) {
 	data, err := idSource.marshalJSON(op == Insert)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not marshal JCIDSource object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not marshal JCIDSource object, err='%w'", err)
 	}
 
 	url := IDSOURCES_PATH
@@ -108,16 +129,16 @@ func (jc JCAPI) AddUpdateIDSource(op JCOp, idSource JCIDSource) (string, JCError
 		url += "/" + idSource.Id
 	}
 
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(op), url, data)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(op), url, data)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not post new JCIDSource object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not post new JCIDSource object, err='%w'", err)
 	}
 
 	var resultES JCIDSource
 
-	err = json.Unmarshal(buffer, &resultES)
+	err = decodeJSON(buffer, &resultES)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not unmarshal result buffer '%s', err='%s'", buffer, err.Error())
+		return "", fmt.Errorf("ERROR: Could not unmarshal result buffer, err='%w'", err)
 	}
 
 	if resultES.Name != idSource.Name {
@@ -128,9 +149,13 @@ func (jc JCAPI) AddUpdateIDSource(op JCOp, idSource JCIDSource) (string, JCError
 }
 
 func (jc JCAPI) DeleteIDSource(idSource JCIDSource) JCError {
-	_, err := jc.Delete(fmt.Sprintf("%s/%s", IDSOURCES_PATH, idSource.Id))
+	return jc.DeleteIDSourceContext(context.Background(), idSource)
+}
+
+func (jc JCAPI) DeleteIDSourceContext(ctx context.Context, idSource JCIDSource) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("%s/%s", IDSOURCES_PATH, idSource.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete ID source ID '%s': err='%s'", idSource.Id, err)
+		return fmt.Errorf("ERROR: Could not delete ID source ID '%s': err='%w'", idSource.Id, err)
 	}
 
 	return nil
@@ -0,0 +1,95 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// The characters most likely to break JSON built by hand
+var adversarialRunes = []rune("\"\\/{}[]:,'` \t\r\n\x00\x1f\x7f<>&\u2028\u2029\ufeffé€𝄞aZ0")
+
+//
+// adversarialString is a string made mostly of adversarialRunes, with the odd rune
+// picked from below the surrogate range, for use with testing/quick.
+//
+type adversarialString string
+
+func (adversarialString) Generate(r *rand.Rand, size int) reflect.Value {
+	var builder strings.Builder
+
+	for i := r.Intn(size + 1); i > 0; i-- {
+		if r.Intn(8) == 0 {
+			builder.WriteRune(rune(r.Intn(0xD800)))
+		} else {
+			builder.WriteRune(adversarialRunes[r.Intn(len(adversarialRunes))])
+		}
+	}
+
+	return reflect.ValueOf(adversarialString(builder.String()))
+}
+
+func TestIDSourceMarshalRoundTrip(t *testing.T) {
+	roundTrip := func(id, name, org, dn, ipAddress adversarialString, lastUpdate uint32, active, writeActive bool) bool {
+		idSource := JCIDSource{
+			Id:             string(id),
+			Name:           string(name),
+			Organization:   string(org),
+			Type:           "ldap",
+			Version:        "1.0",
+			IpAddress:      string(ipAddress),
+			LastUpdateTime: NewTimestamp(time.Unix(int64(lastUpdate), 0).UTC()),
+			DN:             string(dn),
+			Active:         active,
+		}
+
+		data, err := idSource.marshalJSON(writeActive)
+		if err != nil || !json.Valid(data) {
+			t.Logf("Invalid JSON '%s' for %+v, err='%v'", data, idSource, err)
+			return false
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return false
+		}
+
+		// 'active' is only ever written on a POST, and then as a real boolean
+		activeValue, hasActive := fields["active"]
+		if hasActive != writeActive || (hasActive && activeValue != active) {
+			t.Logf("Unexpected active field in '%s' (writeActive=%t)", data, writeActive)
+			return false
+		}
+
+		var decoded JCIDSource
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return false
+		}
+
+		if !writeActive {
+			decoded.Active = active
+		}
+
+		// The decoded time keeps the JSON it came from, so compare the instants instead
+		if !decoded.LastUpdateTime.Equal(idSource.LastUpdateTime) {
+			t.Logf("Round trip of %s returned %s", idSource.LastUpdateTime, decoded.LastUpdateTime)
+			return false
+		}
+		decoded.LastUpdateTime = idSource.LastUpdateTime
+
+		if decoded != idSource {
+			t.Logf("Round trip of %+v returned %+v", idSource, decoded)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
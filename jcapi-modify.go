@@ -0,0 +1,218 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	// Number of times ModifyUser() and friends read and mutate an object JumpCloud
+	// refuses to update as conflicting before giving up, unless changed with
+	// WithModifyAttempts()
+	DefaultModifyAttempts int = 3
+)
+
+// WithModifyAttempts sets how many times ModifyUser() and friends try to apply a change.
+func WithModifyAttempts(attempts int) Option {
+	return func(cfg *clientConfig) {
+		cfg.modifyAttempts = attempts
+	}
+}
+
+//
+// modifyOps plugs one kind of object into modify(). The object read from path is
+// decoded twice, into original and into desired, so that mutate can change desired
+// without touching original.
+//
+type modifyOps struct {
+	kind string // "user", "system" or "tag", for the error messages
+	path string // where the object is read from
+
+	original interface{} // pointers to the objects decoded
+	desired  interface{}
+
+	mutate func() error                                 // changes desired
+	diff   func() map[string]interface{}                // the changes from original to desired
+	write  func(changes map[string]interface{}) JCError // sends the changes
+}
+
+//
+// modify reads the object, applies the mutation to it and writes back only the fields
+// the mutation changed, so that the edits made by others to the other fields of the
+// object in the meantime are kept. That is the only protection against concurrent edits:
+// the JumpCloud API has no version of the objects to make the write conditional on, so
+// a field changed by someone else between the read and the write is overwritten when the
+// mutation changes it too. When JumpCloud refuses the write as conflicting, the object is
+// read and mutated again, up to jc.modifyAttempts times.
+//
+func (jc JCAPI) modify(ctx context.Context, ops modifyOps) JCError {
+	attempts := jc.modifyAttempts
+	if attempts < 1 {
+		attempts = DefaultModifyAttempts
+	}
+
+	var err JCError
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		err = jc.readObject(ctx, ops)
+		if err != nil {
+			return err
+		}
+
+		idBefore := jsonFieldValues(ops.desired)["_id"]
+
+		err = ops.mutate()
+		if err != nil {
+			return err
+		}
+
+		if jsonFieldValues(ops.desired)["_id"] != idBefore {
+			return fmt.Errorf("ERROR: Cannot change the ID of %s '%s'", ops.kind, ops.path)
+		}
+
+		changes := ops.diff()
+		if len(changes) == 0 {
+			return nil
+		}
+
+		err = ops.write(changes)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("ERROR: JumpCloud kept refusing to update %s '%s' as conflicting, err='%w'", ops.kind, ops.path, err)
+}
+
+// readObject reads the object into both ops.original and ops.desired.
+func (jc JCAPI) readObject(ctx context.Context, ops modifyOps) JCError {
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), ops.path, nil)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not read %s '%s', err='%w'", ops.kind, ops.path, err)
+	}
+
+	for _, v := range []interface{}{ops.original, ops.desired} {
+		reflect.ValueOf(v).Elem().Set(reflect.Zero(reflect.TypeOf(v).Elem()))
+
+		err = decodeJSON(buffer, v)
+		if err != nil {
+			return fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
+		}
+	}
+
+	return nil
+}
+
+//
+// putFields sends only the given fields of the object at urlPath to JumpCloud, and
+// decodes the updated object into v.
+//
+func (jc JCAPI) putFields(ctx context.Context, urlPath string, fields map[string]interface{}, v interface{}) JCError {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not marshal changes to '%s', err='%w'", urlPath, err)
+	}
+
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Update), urlPath, data)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not update '%s', err='%w'", urlPath, err)
+	}
+
+	err = decodeJSON(buffer, v)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
+	}
+
+	return nil
+}
+
+func (jc JCAPI) ModifyUser(userId string, mutate func(*JCUser) error) (JCUser, JCError) {
+	return jc.ModifyUserContext(context.Background(), userId, mutate)
+}
+
+//
+// ModifyUserContext reads the system user, lets mutate change it and sends back only the
+// fields it changed, keeping the other fields as they are on JumpCloud by then. mutate
+// may be called more than once, on a fresh copy of the user each time, when JumpCloud
+// refuses the update as conflicting; once the attempts run out, the error returned
+// matches ErrConflict. It returns the user as updated by JumpCloud, or as read when
+// mutate changed nothing.
+//
+func (jc JCAPI) ModifyUserContext(ctx context.Context, userId string, mutate func(*JCUser) error) (user JCUser, err JCError) {
+	var original, desired JCUser
+
+	err = jc.modify(ctx, modifyOps{
+		kind:     "user",
+		path:     "/systemusers/" + userId,
+		original: &original,
+		desired:  &desired,
+		mutate:   func() error { return mutate(&desired) },
+		diff:     func() map[string]interface{} { return DiffUsers(original, desired) },
+		write: func(changes map[string]interface{}) (err JCError) {
+			original, err = jc.UpdateUserFieldsContext(ctx, userId, changes)
+			return
+		},
+	})
+
+	return original, err
+}
+
+func (jc JCAPI) ModifySystem(systemId string, mutate func(*JCSystem) error) (JCSystem, JCError) {
+	return jc.ModifySystemContext(context.Background(), systemId, mutate)
+}
+
+//
+// ModifySystemContext is ModifyUserContext() for systems. The fields the agent reports
+// on every check-in, such as lastContact, are only sent when mutate changes them.
+//
+func (jc JCAPI) ModifySystemContext(ctx context.Context, systemId string, mutate func(*JCSystem) error) (system JCSystem, err JCError) {
+	var original, desired JCSystem
+
+	url := SYSTEMS_PATH + "/" + systemId
+
+	err = jc.modify(ctx, modifyOps{
+		kind:     "system",
+		path:     url,
+		original: &original,
+		desired:  &desired,
+		mutate:   func() error { return mutate(&desired) },
+		diff:     func() map[string]interface{} { return diffFields(original, desired) },
+		write: func(changes map[string]interface{}) JCError {
+			original = JCSystem{}
+			return jc.putFields(ctx, url, changes, &original)
+		},
+	})
+
+	return original, err
+}
+
+func (jc JCAPI) ModifyTag(tagName string, mutate func(*JCTag) error) (JCTag, JCError) {
+	return jc.ModifyTagContext(context.Background(), tagName, mutate)
+}
+
+//
+// ModifyTagContext is ModifyUserContext() for tags, which are looked up by name the
+// same way GetTagByName() does.
+//
+func (jc JCAPI) ModifyTagContext(ctx context.Context, tagName string, mutate func(*JCTag) error) (tag JCTag, err JCError) {
+	var original, desired JCTag
+
+	err = jc.modify(ctx, modifyOps{
+		kind:     "tag",
+		path:     TAGS_PATH + "/" + tagName,
+		original: &original,
+		desired:  &desired,
+		mutate:   func() error { return mutate(&desired) },
+		diff:     func() map[string]interface{} { return diffFields(original, desired) },
+		write: func(changes map[string]interface{}) JCError {
+			url := TAGS_PATH + "/" + original.Id
+			original = JCTag{}
+			return jc.putFields(ctx, url, changes, &original)
+		},
+	})
+
+	return original, err
+}
@@ -0,0 +1,269 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//
+// objectServer serves the JSON objects on their path, applies the fields PUT to them,
+// and calls onGet (when set) on every GET, before answering, to simulate other editors.
+// It refuses the first conflicts PUTs with a 409.
+//
+type objectServer struct {
+	sync.Mutex
+
+	objects   map[string]map[string]interface{}
+	onGet     func(path string, object map[string]interface{})
+	conflicts int
+	gets      int
+	puts      []string
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	object, ok := s.objects[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.gets++
+		if s.onGet != nil {
+			s.onGet(r.URL.Path, object)
+		}
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		s.puts = append(s.puts, r.URL.Path+" "+string(body))
+
+		if s.conflicts > 0 {
+			s.conflicts--
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		var fields map[string]interface{}
+		json.Unmarshal(body, &fields)
+
+		for name, value := range fields {
+			object[name] = value
+		}
+	}
+
+	json.NewEncoder(w).Encode(object)
+}
+
+func newObjectServer(t *testing.T, objects map[string]map[string]interface{}) (*objectServer, JCAPI) {
+	server := &objectServer{objects: objects}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, NewJCAPI("key", ts.URL)
+}
+
+func TestModifyUserSendsOnlyTheChanges(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe", "sudo": true, "activated": true, "unix_uid": 5000},
+	})
+
+	user, err := jc.ModifyUser("a", func(user *JCUser) error {
+		user.Attributes = append(user.Attributes, JCUserAttribute{"dept", "sales"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyUser() failed, err='%s'", err)
+	}
+
+	if !user.Sudo || len(user.Attributes) != 1 || user.Attributes[0].Value != "sales" {
+		t.Fatalf("Unexpected user returned: %+v", user)
+	}
+
+	expected := `/systemusers/a {"attributes":[{"name":"dept","value":"sales"}]}`
+	if len(server.puts) != 1 || server.puts[0] != expected {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+
+	// Nothing to change, nothing sent
+	_, err = jc.ModifyUser("a", func(user *JCUser) error { return nil })
+	if err != nil || len(server.puts) != 1 {
+		t.Fatalf("Expected no update, got %v, err='%v'", server.puts, err)
+	}
+}
+
+func TestModifyUserKeepsConcurrentEdits(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe", "lastname": "Doe"},
+	})
+
+	user, err := jc.ModifyUser("a", func(user *JCUser) error {
+		// An admin renames the user between our read and our write
+		server.Lock()
+		server.objects["/systemusers/a"]["lastname"] = "Smith"
+		server.Unlock()
+
+		user.FirstName = "John"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyUser() failed, err='%s'", err)
+	}
+
+	if user.FirstName != "John" || user.LastName != "Smith" {
+		t.Fatalf("Unexpected user returned: %+v", user)
+	}
+
+	if len(server.puts) != 1 || server.puts[0] != `/systemusers/a {"firstname":"John"}` {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+}
+
+func TestModifyUserRetriesConflicts(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe", "lastname": "Doe"},
+	})
+
+	server.conflicts = 1
+
+	// The user is read again for the second attempt
+	server.onGet = func(path string, object map[string]interface{}) {
+		if server.gets == 2 {
+			object["lastname"] = "Smith"
+		}
+	}
+
+	var calls []string
+
+	user, err := jc.ModifyUser("a", func(user *JCUser) error {
+		calls = append(calls, user.LastName)
+		user.FirstName = "John " + user.LastName
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyUser() failed, err='%s'", err)
+	}
+
+	if strings.Join(calls, " ") != "Doe Smith" || user.FirstName != "John Smith" || user.LastName != "Smith" {
+		t.Fatalf("Unexpected mutations %v, user %+v", calls, user)
+	}
+
+	expected := []string{`/systemusers/a {"firstname":"John Doe"}`, `/systemusers/a {"firstname":"John Smith"}`}
+	if strings.Join(server.puts, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+}
+
+func TestModifyUserGivesUpOnConflicts(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe", "unix_uid": 5000},
+	})
+
+	server.conflicts = 10
+
+	jc.modifyAttempts = 4
+	calls := 0
+
+	_, err := jc.ModifyUser("a", func(user *JCUser) error {
+		calls++
+		user.Sudo = true
+		return nil
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected a conflict, got '%v'", err)
+	}
+
+	if calls != 4 || len(server.puts) != 4 || server.gets != 4 {
+		t.Fatalf("Expected 4 attempts, got %d, %d reads and %v", calls, server.gets, server.puts)
+	}
+}
+
+func TestModifyUserReturnsMutationErrors(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe"},
+	})
+
+	failure := fmt.Errorf("no such department")
+
+	_, err := jc.ModifyUser("a", func(user *JCUser) error {
+		user.Sudo = true
+		return failure
+	})
+	if err != failure || len(server.puts) != 0 {
+		t.Fatalf("Expected the mutation error and no update, got '%v' and %v", err, server.puts)
+	}
+
+	_, err = jc.ModifyUser("a", func(user *JCUser) error {
+		user.Id = "b"
+		return nil
+	})
+	if err == nil || len(server.puts) != 0 {
+		t.Fatalf("Expected an error changing the ID, got '%v' and %v", err, server.puts)
+	}
+
+	_, err = jc.ModifyUser("b", func(user *JCUser) error { return nil })
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected a not found error, got '%v'", err)
+	}
+}
+
+func TestModifySystemIgnoresCheckIns(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systems/s1": {"_id": "s1", "hostname": "web1", "displayName": "web1", "allowSshRootLogin": true},
+	})
+
+	server.onGet = func(path string, object map[string]interface{}) {
+		object["lastContact"] = fmt.Sprintf("2016-05-04T03:02:%02dZ", server.gets)
+	}
+
+	system, err := jc.ModifySystem("s1", func(system *JCSystem) error {
+		system.DisplayName = "web1 (prod)"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifySystem() failed, err='%s'", err)
+	}
+
+	if system.DisplayName != "web1 (prod)" || !system.AllowSshRootLogin {
+		t.Fatalf("Unexpected system returned: %+v", system)
+	}
+
+	if len(server.puts) != 1 || server.puts[0] != `/systems/s1 {"displayName":"web1 (prod)"}` {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+}
+
+func TestModifyTag(t *testing.T) {
+	tag := map[string]interface{}{"_id": "t1", "name": "web", "systems": []string{"s1"}}
+
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/tags/web": tag,
+		"/tags/t1":  tag,
+	})
+
+	result, err := jc.ModifyTag("web", func(tag *JCTag) error {
+		tag.Systems = append(tag.Systems, "s2")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyTag() failed, err='%s'", err)
+	}
+
+	if result.Id != "t1" || strings.Join(result.Systems, ",") != "s1,s2" {
+		t.Fatalf("Unexpected tag returned: %+v", result)
+	}
+
+	if len(server.puts) != 1 || server.puts[0] != `/tags/t1 {"systems":["s1","s2"]}` {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+}
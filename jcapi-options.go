@@ -0,0 +1,171 @@
+package jcapi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//
+// All JCAPI objects built by NewJCAPI() share this client unless they are given
+// one of their own, so connections to JumpCloud are pooled and reused.
+//
+var defaultHTTPClient = &http.Client{}
+
+// Option configures a JCAPI object built with NewJCAPIWithOptions()
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	proxy      func(*http.Request) (*url.URL, error)
+	tlsConfig  *tls.Config
+	userAgent  string
+
+	retryPolicy *RetryPolicy
+	rateLimiter *RateLimiter
+
+	responseHook func(*ResponseMeta)
+
+	detailConcurrency int
+	modifyAttempts    int
+
+	passwordPolicy *PasswordPolicy
+}
+
+// WithHTTPClient makes the JCAPI object send every request through client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *clientConfig) {
+		cfg.httpClient = client
+	}
+}
+
+// WithTransport sets the RoundTripper used to send requests, which is handy
+// for injecting a test transport or instrumenting the client.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(cfg *clientConfig) {
+		cfg.transport = transport
+	}
+}
+
+// WithTimeout sets an overall time limit on each HTTP request, including reading the body.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *clientConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent on every request.
+func WithUserAgent(userAgent string) Option {
+	return func(cfg *clientConfig) {
+		cfg.userAgent = userAgent
+	}
+}
+
+// WithProxy sends every request through the given proxy URL.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(cfg *clientConfig) {
+		cfg.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithProxyFunc selects a proxy per request, e.g. http.ProxyFromEnvironment.
+func WithProxyFunc(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(cfg *clientConfig) {
+		cfg.proxy = proxy
+	}
+}
+
+// WithTLSConfig sets the TLS configuration (custom root CAs, client certificates...)
+// used when connecting to JumpCloud.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(cfg *clientConfig) {
+		cfg.tlsConfig = tlsConfig
+	}
+}
+
+//
+// NewJCAPIWithOptions builds a JCAPI object the same way NewJCAPI() does, and then
+// applies the given options to it. A single HTTP client is built from the options
+// and shared by every call made through the returned object, including AuthUser().
+//
+func NewJCAPIWithOptions(apiKey string, urlBase string, opts ...Option) (JCAPI, error) {
+	jc := NewJCAPI(apiKey, urlBase)
+
+	cfg := clientConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client, err := cfg.buildClient()
+	if err != nil {
+		return jc, err
+	}
+
+	jc.client = client
+	jc.userAgent = cfg.userAgent
+
+	if cfg.retryPolicy != nil {
+		jc.retryPolicy = cfg.retryPolicy
+	}
+
+	jc.rateLimiter = cfg.rateLimiter
+	jc.responseHook = cfg.responseHook
+	jc.detailConcurrency = cfg.detailConcurrency
+	jc.modifyAttempts = cfg.modifyAttempts
+	jc.passwordPolicy = cfg.passwordPolicy
+
+	return jc, nil
+}
+
+func (cfg clientConfig) buildClient() (*http.Client, error) {
+	client := &http.Client{}
+	if cfg.httpClient != nil {
+		// Copy the caller's client so that our changes below don't leak back into it
+		*client = *cfg.httpClient
+	}
+
+	if cfg.transport != nil {
+		client.Transport = cfg.transport
+	}
+
+	if cfg.timeout > 0 {
+		client.Timeout = cfg.timeout
+	}
+
+	if cfg.proxy != nil || cfg.tlsConfig != nil {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+
+		transport, ok := base.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("ERROR: Cannot set a proxy or TLS config on a transport of type %T", base)
+		}
+
+		transport = transport.Clone()
+
+		if cfg.proxy != nil {
+			transport.Proxy = cfg.proxy
+		}
+		if cfg.tlsConfig != nil {
+			transport.TLSClientConfig = cfg.tlsConfig
+		}
+
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
+func (jc JCAPI) httpClient() *http.Client {
+	if jc.client != nil {
+		return jc.client
+	}
+
+	return defaultHTTPClient
+}
@@ -0,0 +1,125 @@
+package jcapi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingTransport struct {
+	requests []*http.Request
+	status   int
+	reason   string
+	body     string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+
+	status := rt.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	reason := rt.reason
+	if reason == "" {
+		reason = http.StatusText(status)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, reason),
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(rt.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestOptionsShareOneClient(t *testing.T) {
+	rt := &recordingTransport{body: `{"results":[]}`}
+
+	jc, err := NewJCAPIWithOptions("fake-key", "https://jumpcloud.test/api", WithTransport(rt), WithUserAgent("jcapi-test/1.0"))
+	if err != nil {
+		t.Fatalf("Could not build JCAPI object, err='%s'", err)
+	}
+
+	_, err = jc.DoBytes(MapJCOpToHTTP(Read), TAGS_PATH, nil)
+	if err != nil {
+		t.Fatalf("DoBytes() failed, err='%s'", err)
+	}
+
+	// The auth endpoint must go through the very same transport
+	_, err = jc.AuthUser("someone", "secret", "")
+	if err != nil {
+		t.Fatalf("AuthUser() failed, err='%s'", err)
+	}
+
+	if len(rt.requests) != 2 {
+		t.Fatalf("Expected 2 requests through the injected transport, got %d", len(rt.requests))
+	}
+
+	for _, req := range rt.requests {
+		if ua := req.Header.Get("User-Agent"); ua != "jcapi-test/1.0" {
+			t.Fatalf("Request to '%s' sent User-Agent '%s'", req.URL, ua)
+		}
+		if key := req.Header.Get("x-api-key"); key != "fake-key" {
+			t.Fatalf("Request to '%s' sent x-api-key '%s'", req.URL, key)
+		}
+	}
+
+	if rt.requests[1].URL.Path != "/api"+AUTHENTICATE_PATH {
+		t.Fatalf("Expected the second request to go to the auth path, got '%s'", rt.requests[1].URL.Path)
+	}
+}
+
+func TestOptionsBuildTransport(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+	tlsConfig := &tls.Config{ServerName: "console.jumpcloud.com"}
+
+	callerClient := &http.Client{}
+
+	jc, err := NewJCAPIWithOptions("fake-key", StdUrlBase, WithHTTPClient(callerClient),
+		WithTimeout(5*time.Second), WithProxy(proxyURL), WithTLSConfig(tlsConfig))
+	if err != nil {
+		t.Fatalf("Could not build JCAPI object, err='%s'", err)
+	}
+
+	client := jc.httpClient()
+	if client == callerClient || callerClient.Timeout != 0 || callerClient.Transport != nil {
+		t.Fatalf("The caller's client should not be modified by the options")
+	}
+
+	if client.Timeout != 5*time.Second {
+		t.Fatalf("Expected a 5s timeout, got %s", client.Timeout)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", client.Transport)
+	}
+
+	if transport.TLSClientConfig != tlsConfig {
+		t.Fatalf("TLS config was not applied to the transport")
+	}
+
+	req, _ := http.NewRequest("GET", StdUrlBase, nil)
+	if got, _ := transport.Proxy(req); got == nil || got.String() != proxyURL.String() {
+		t.Fatalf("Expected proxy '%s', got '%v'", proxyURL, got)
+	}
+
+	_, err = NewJCAPIWithOptions("fake-key", StdUrlBase, WithTransport(&recordingTransport{}), WithProxy(proxyURL))
+	if err == nil {
+		t.Fatalf("Setting a proxy on a custom RoundTripper should fail")
+	}
+}
+
+func TestNewJCAPIUsesSharedClient(t *testing.T) {
+	if NewJCAPI("a", StdUrlBase).httpClient() != NewJCAPI("b", StdUrlBase).httpClient() {
+		t.Fatalf("JCAPI objects built by NewJCAPI() should share the default client")
+	}
+}
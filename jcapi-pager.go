@@ -0,0 +1,194 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PageOptions controls how a Pager walks a list endpoint
+type PageOptions struct {
+	PageSize int    // items requested per page, defaults to 100
+	Sort     string // field to sort on, prefix with '-' for descending order
+	Limit    int    // stop after this many items, 0 to walk the whole list
+}
+
+// JCPage is the envelope JumpCloud wraps around every page of a list
+type JCPage struct {
+	TotalCount int               `json:"totalCount"`
+	Results    []json.RawMessage `json:"results"`
+}
+
+//
+// Pager walks a JumpCloud list endpoint one page at a time, using skip/limit. Use it
+// like a bufio.Scanner:
+//
+//	pager := jc.NewPager(ctx, SYSTEMS_PATH, PageOptions{Sort: "hostname"})
+//	for pager.Next() {
+//		var system JCSystem
+//		if err := pager.Decode(&system); err != nil {
+//			...
+//		}
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+//
+// A Pager is not safe for concurrent use.
+//
+type Pager struct {
+	jc     JCAPI
+	ctx    context.Context
+	method string
+	path   string
+	body   []byte // sent with every page request, see NewSearchPager()
+	opts   PageOptions
+
+	page       []json.RawMessage
+	index      int
+	skip       int
+	seen       int
+	totalCount int
+	lastPage   bool
+	stopped    bool
+
+	value json.RawMessage
+	err   error
+}
+
+//
+// NewPager returns a Pager over the list at urlPath, which may already carry query
+// parameters of its own. No request is sent until the first call to Next().
+//
+func (jc JCAPI) NewPager(ctx context.Context, urlPath string, opts PageOptions) *Pager {
+	if opts.PageSize <= 0 {
+		opts.PageSize = searchLimit
+	}
+
+	return &Pager{
+		jc:         jc,
+		ctx:        ctx,
+		method:     MapJCOpToHTTP(Read),
+		path:       urlPath,
+		opts:       opts,
+		totalCount: -1,
+	}
+}
+
+// Next advances to the next item, fetching a new page when needed. It returns false
+// at the end of the list, after Stop(), or on error.
+func (p *Pager) Next() bool {
+	p.value = nil
+
+	if p.stopped || p.err != nil {
+		return false
+	}
+
+	if p.opts.Limit > 0 && p.seen >= p.opts.Limit {
+		return false
+	}
+
+	for p.index >= len(p.page) {
+		if p.lastPage {
+			return false
+		}
+
+		if err := p.fetch(); err != nil {
+			p.err = err
+			return false
+		}
+	}
+
+	p.value = p.page[p.index]
+	p.index++
+	p.seen++
+
+	return true
+}
+
+func (p *Pager) fetch() error {
+	// Stop between pages if the caller has given up on us
+	if p.ctx.Err() != nil {
+		return p.ctx.Err()
+	}
+
+	buffer, err := p.jc.DoBytesContext(p.ctx, p.method, p.pageURL(), p.body)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not get page at skip=%d from '%s', err='%w'", p.skip, p.path, err)
+	}
+
+	page := JCPage{TotalCount: -1}
+
+	err = decodeJSON(buffer, &page)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not unmarshal page buffer, err='%w'", err)
+	}
+
+	p.page = page.Results
+	p.index = 0
+	p.skip += len(page.Results)
+
+	if page.TotalCount >= 0 {
+		p.totalCount = page.TotalCount
+	}
+
+	// A short page is the last one, whatever the total count says
+	p.lastPage = len(page.Results) < p.opts.PageSize || (p.totalCount >= 0 && p.skip >= p.totalCount)
+
+	return nil
+}
+
+func (p *Pager) pageURL() string {
+	separator := "?"
+	if strings.Contains(p.path, "?") {
+		separator = "&"
+	}
+
+	limit := p.opts.PageSize
+	if p.opts.Limit > 0 && p.opts.Limit-p.seen < limit {
+		limit = p.opts.Limit - p.seen
+	}
+
+	pageURL := fmt.Sprintf("%s%sskip=%d&limit=%d", p.path, separator, p.skip, limit)
+	if p.opts.Sort != "" {
+		pageURL += "&sort=" + url.QueryEscape(p.opts.Sort)
+	}
+
+	return pageURL
+}
+
+// Value returns the raw JSON of the current item.
+func (p *Pager) Value() json.RawMessage {
+	return p.value
+}
+
+// Decode unmarshals the current item into v.
+func (p *Pager) Decode(v interface{}) error {
+	if p.value == nil {
+		return fmt.Errorf("ERROR: Decode() called without a current item")
+	}
+
+	err := decodeJSONAt(p.value, fmt.Sprintf("$.results[%d]", p.index-1), v)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not unmarshal item, err='%w'", err)
+	}
+
+	return nil
+}
+
+// Err returns the error that ended the walk, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// Stop ends the walk early, no further pages are fetched.
+func (p *Pager) Stop() {
+	p.stopped = true
+}
+
+// TotalCount returns the total number of items JumpCloud reported, or -1 if unknown.
+func (p *Pager) TotalCount() int {
+	return p.totalCount
+}
@@ -0,0 +1,125 @@
+package jcapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newListServer serves count items from every list endpoint, honoring skip and limit
+func newListServer(t *testing.T, count int, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			*requests = append(*requests, r.URL.RequestURI())
+		}
+
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var results []string
+		for i := skip; i < count && i < skip+limit; i++ {
+			results = append(results, fmt.Sprintf(`{"_id":"id%d","name":"item%d","hostname":"host%d","username":"user%d","email":"user%d@example.com","sudo":false}`, i, i, i, i, i))
+		}
+
+		fmt.Fprintf(w, `{"totalCount":%d,"results":[%s]}`, count, strings.Join(results, ","))
+	}))
+}
+
+func TestPagerWalksAllPages(t *testing.T) {
+	var requests []string
+	ts := newListServer(t, 250, &requests)
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+	pager := jc.NewPager(context.Background(), SYSTEMS_PATH, PageOptions{Sort: "hostname"})
+
+	count := 0
+	for pager.Next() {
+		var system JCSystem
+		if err := pager.Decode(&system); err != nil {
+			t.Fatalf("Decode() failed, err='%s'", err)
+		}
+
+		if system.Id != fmt.Sprintf("id%d", count) {
+			t.Fatalf("Expected item %d, got '%s'", count, system.Id)
+		}
+		count++
+	}
+
+	if pager.Err() != nil {
+		t.Fatalf("Pager failed, err='%s'", pager.Err())
+	}
+
+	if count != 250 || pager.TotalCount() != 250 {
+		t.Fatalf("Expected 250 items, got %d (total count %d)", count, pager.TotalCount())
+	}
+
+	if len(requests) != 3 || requests[2] != SYSTEMS_PATH+"?skip=200&limit=100&sort=hostname" {
+		t.Fatalf("Unexpected page requests: %v", requests)
+	}
+}
+
+func TestPagerEarlyStop(t *testing.T) {
+	var requests []string
+	ts := newListServer(t, 250, &requests)
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	pager := jc.NewPager(context.Background(), TAGS_PATH+"?fields=name", PageOptions{PageSize: 20, Limit: 30})
+
+	count := 0
+	for pager.Next() {
+		count++
+	}
+
+	if count != 30 {
+		t.Fatalf("Expected the pager to stop after 30 items, got %d", count)
+	}
+
+	if len(requests) != 2 || requests[1] != TAGS_PATH+"?fields=name&skip=20&limit=10" {
+		t.Fatalf("Unexpected page requests: %v", requests)
+	}
+
+	requests = nil
+	pager = jc.NewPager(context.Background(), TAGS_PATH, PageOptions{PageSize: 20})
+
+	for pager.Next() {
+		pager.Stop()
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Stop() should prevent any further page fetch, got %v", requests)
+	}
+}
+
+func TestGetAllPastOneHundred(t *testing.T) {
+	ts := newListServer(t, 230, nil)
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	tags, err := jc.GetAllTags()
+	if err != nil || len(tags) != 230 {
+		t.Fatalf("GetAllTags() returned %d tags, err='%v'", len(tags), err)
+	}
+
+	systems, err := jc.GetSystems(false)
+	if err != nil || len(systems) != 230 {
+		t.Fatalf("GetSystems() returned %d systems, err='%v'", len(systems), err)
+	}
+
+	commands, err := jc.GetAllCommands()
+	if err != nil || len(commands) != 230 {
+		t.Fatalf("GetAllCommands() returned %d commands, err='%v'", len(commands), err)
+	}
+
+	results, err := jc.GetCommandResultsByName("item")
+	if err != nil || len(results) != 230 {
+		t.Fatalf("GetCommandResultsByName() returned %d results, err='%v'", len(results), err)
+	}
+}
@@ -0,0 +1,231 @@
+package jcapi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//
+// The rules of a PasswordPolicy, as reported in PasswordViolation.Rule
+//
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleLower     = "lowercase"
+	PasswordRuleUpper     = "uppercase"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleUsername  = "username"
+	PasswordRuleEmail     = "email"
+	PasswordRuleBanned    = "banned"
+)
+
+// The shortest username or email local part that a password is checked against
+const minSimilarityLength int = 3
+
+//
+// PasswordPolicy checks the password of a user before it is sent to JumpCloud, so that
+// a weak one is rejected with the list of the rules it breaks rather than an opaque
+// status from the API. The zero PasswordPolicy accepts any password. Set it on a JCAPI
+// object with WithPasswordPolicy() to have AddUpdateUser() and UpdateUserFields() apply it.
+//
+type PasswordPolicy struct {
+	MinLength int // in characters, 0 for no minimum
+	MaxLength int // in characters, 0 for no maximum
+
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool // anything that is neither a letter nor a digit
+
+	RejectUserInfo bool // reject passwords that contain the username or email of the user
+
+	banned map[string]bool // lowercased, see LoadBannedList()
+}
+
+// PasswordViolation is a rule of a PasswordPolicy that a password breaks
+type PasswordViolation struct {
+	Rule    string // one of the PasswordRule* constants
+	Message string
+}
+
+// PasswordPolicyError lists every rule of a PasswordPolicy that a password breaks
+type PasswordPolicyError struct {
+	UserName   string
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+
+	for idx, violation := range e.Violations {
+		messages[idx] = violation.Message
+	}
+
+	return fmt.Sprintf("ERROR: Password of user '%s' breaks the password policy: %s", e.UserName, strings.Join(messages, "; "))
+}
+
+// Rules returns the rules broken, in the order they were checked.
+func (e *PasswordPolicyError) Rules() []string {
+	rules := make([]string, len(e.Violations))
+
+	for idx, violation := range e.Violations {
+		rules[idx] = violation.Rule
+	}
+
+	return rules
+}
+
+//
+// DefaultPasswordPolicy returns a policy of at least 8 characters mixing lower and upper
+// case letters and digits, which doesn't contain the username or email of the user.
+//
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:      8,
+		RequireLower:   true,
+		RequireUpper:   true,
+		RequireDigit:   true,
+		RejectUserInfo: true,
+	}
+}
+
+// WithPasswordPolicy makes AddUpdateUser() and UpdateUserFields() check passwords against policy before sending them.
+func WithPasswordPolicy(policy *PasswordPolicy) Option {
+	return func(cfg *clientConfig) {
+		cfg.passwordPolicy = policy
+	}
+}
+
+//
+// LoadBannedList adds the passwords read from r, one per line, to the passwords the
+// policy rejects. They are compared regardless of case, and blank lines are skipped.
+//
+func (p *PasswordPolicy) LoadBannedList(r io.Reader) error {
+	if p.banned == nil {
+		p.banned = make(map[string]bool)
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			p.banned[strings.ToLower(password)] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ERROR: Could not read banned password list, err='%w'", err)
+	}
+
+	return nil
+}
+
+// LoadBannedListFile is LoadBannedList() for the file at path.
+func (p *PasswordPolicy) LoadBannedListFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not open banned password list, err='%w'", err)
+	}
+	defer file.Close()
+
+	return p.LoadBannedList(file)
+}
+
+//
+// Validate checks the password of user against the policy, and returns a
+// *PasswordPolicyError listing every rule it breaks, or nil. A user without a
+// password passes, as JumpCloud then asks them to choose one.
+//
+func (p *PasswordPolicy) Validate(user JCUser) error {
+	password := user.Password
+	if p == nil || password == "" {
+		return nil
+	}
+
+	var violations []PasswordViolation
+
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+
+	if p.MinLength > 0 && length < p.MinLength {
+		violate(PasswordRuleMinLength, "must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(PasswordRuleMaxLength, "must be at most %d characters long", p.MaxLength)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireLower && !hasLower {
+		violate(PasswordRuleLower, "must contain a lowercase letter")
+	}
+	if p.RequireUpper && !hasUpper {
+		violate(PasswordRuleUpper, "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violate(PasswordRuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violate(PasswordRuleSymbol, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+
+	if p.RejectUserInfo {
+		if containsUserInfo(lowered, user.UserName) {
+			violate(PasswordRuleUsername, "must not contain the username")
+		}
+
+		localPart := strings.SplitN(user.Email, "@", 2)[0]
+		if containsUserInfo(lowered, localPart) {
+			violate(PasswordRuleEmail, "must not contain the email address")
+		}
+	}
+
+	if p.banned[lowered] {
+		violate(PasswordRuleBanned, "is a banned password")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{UserName: user.UserName, Violations: violations}
+	}
+
+	return nil
+}
+
+// containsUserInfo reports whether the lowercased password contains info, forwards or backwards.
+func containsUserInfo(password, info string) bool {
+	info = strings.ToLower(info)
+	if utf8.RuneCountInString(info) < minSimilarityLength {
+		return false
+	}
+
+	runes := []rune(info)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return strings.Contains(password, info) || strings.Contains(password, string(runes))
+}
@@ -0,0 +1,120 @@
+package jcapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.MaxLength = 20
+	policy.RequireSymbol = true
+
+	err := policy.LoadBannedList(strings.NewReader("Password1!\n\n  Summer2016!  \n"))
+	if err != nil {
+		t.Fatalf("LoadBannedList() failed, err='%s'", err)
+	}
+
+	tests := []struct {
+		password string
+		rules    string
+	}{
+		{"", ""},
+		{"c0rrect-Horse", ""},
+		{"short", "min_length uppercase digit symbol"},
+		{"ALLUPPERCASE12345678901", "max_length lowercase symbol"},
+		{"My-jdoe-pass9", "username"},
+		{"My-eodj-pass9", "username"},
+		{"John.Smith-99", "email"},
+		{"password1!", "uppercase banned"},
+		{"SUMMER2016!", "lowercase banned"},
+		{"ünïcödé-Pässwörd1", ""},
+	}
+
+	for _, test := range tests {
+		user := JCUser{UserName: "jdoe", Email: "john.smith@example.com", Password: test.password}
+
+		err := policy.Validate(user)
+
+		var policyErr *PasswordPolicyError
+		if test.rules == "" {
+			if err != nil {
+				t.Errorf("Expected '%s' to pass, got '%s'", test.password, err)
+			}
+			continue
+		}
+
+		if !errors.As(err, &policyErr) {
+			t.Errorf("Expected a *PasswordPolicyError for '%s', got '%v'", test.password, err)
+			continue
+		}
+
+		if rules := strings.Join(policyErr.Rules(), " "); rules != test.rules {
+			t.Errorf("Unexpected rules broken by '%s': got '%s', want '%s'", test.password, rules, test.rules)
+		}
+	}
+
+	// Short usernames don't make every password that contains them weak
+	if err := policy.Validate(JCUser{UserName: "jo", Email: "x@y.z", Password: "Enjoy-2016!"}); err != nil {
+		t.Errorf("Unexpected error '%s'", err)
+	}
+
+	var nilPolicy *PasswordPolicy
+	if err := nilPolicy.Validate(JCUser{Password: "a"}); err != nil {
+		t.Errorf("A nil policy should accept anything, got '%s'", err)
+	}
+}
+
+func TestAddUpdateUserChecksPasswordPolicy(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","email":"jdoe@example.com"}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt), WithPasswordPolicy(DefaultPasswordPolicy()))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	user := JCUser{UserName: "jdoe", Email: "jdoe@example.com", Password: "jdoe"}
+
+	_, err = jc.AddUpdateUser(Insert, user)
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 5 || len(rt.requests) != 0 {
+		t.Fatalf("Expected 5 violations and no request, got '%v' and %d requests", err, len(rt.requests))
+	}
+
+	user.Password = "Tr0ub4dor&3"
+
+	userId, err := jc.AddUpdateUser(Insert, user)
+	if err != nil || userId != "a" || len(rt.requests) != 1 {
+		t.Fatalf("AddUpdateUser() returned '%s', err='%v'", userId, err)
+	}
+}
+
+func TestUpdateUserFieldsChecksPasswordPolicy(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","username":"jdoe","email":"jdoe@example.com"}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt), WithPasswordPolicy(DefaultPasswordPolicy()))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	_, err = jc.UpdateUserFields("a", JCUserChanges{"password": "weak"})
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) || len(rt.requests) != 0 {
+		t.Fatalf("Expected a *PasswordPolicyError and no request, got '%v' and %d requests", err, len(rt.requests))
+	}
+
+	// A password that only breaks the rules on the user info is checked against the user read back
+	_, err = jc.UpdateUserFields("a", JCUserChanges{"password": "Jdoe2016!"})
+	if !errors.As(err, &policyErr) || policyErr.Rules()[0] != PasswordRuleUsername || len(rt.requests) != 1 || rt.requests[0].Method != http.MethodGet {
+		t.Fatalf("Expected the username rule checked against the current user, got '%v'", err)
+	}
+
+	_, err = jc.UpdateUserFields("a", JCUserChanges{"password": "Tr0ub4dor&3"})
+	if err != nil || len(rt.requests) != 3 || rt.requests[2].Method != http.MethodPut {
+		t.Fatalf("UpdateUserFields() failed, err='%v'", err)
+	}
+}
@@ -1,6 +1,7 @@
 package jcapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -27,17 +28,21 @@ func (e JCRadiusServer) ToString() string {
 }
 
 func (jc JCAPI) GetAllRadiusServers() (radiusServers []JCRadiusServer, err JCError) {
-	result, err := jc.DoBytes(MapJCOpToHTTP(Read), RADIUS_SERVERS_PATH, nil)
+	return jc.GetAllRadiusServersContext(context.Background())
+}
+
+func (jc JCAPI) GetAllRadiusServersContext(ctx context.Context) (radiusServers []JCRadiusServer, err JCError) {
+	result, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), RADIUS_SERVERS_PATH, nil)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not list RADIUS servers, err='%s'", err)
+		err = fmt.Errorf("ERROR: Could not list RADIUS servers, err='%w'", err)
 		return
 	}
 
 	radiusResults := JCRadiusServerResults{}
 
-	err = json.Unmarshal(result, &radiusResults)
+	err = decodeJSON(result, &radiusResults)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not unmarshal result buffer '%s', err='%s'", result, err.Error())
+		err = fmt.Errorf("ERROR: Could not unmarshal result buffer, err='%w'", err)
 		return
 	}
 
@@ -68,9 +73,13 @@ func GetInterfaceArrayFromJCRadiusServer(radiusServers []JCRadiusServer) (interf
 // Add or Update a radiusserver in place on JumpCloud
 //
 func (jc JCAPI) AddUpdateRadiusServer(op JCOp, radiusServer JCRadiusServer) (id string, err JCError) {
+	return jc.AddUpdateRadiusServerContext(context.Background(), op, radiusServer)
+}
+
+func (jc JCAPI) AddUpdateRadiusServerContext(ctx context.Context, op JCOp, radiusServer JCRadiusServer) (id string, err JCError) {
 	data, err := json.Marshal(radiusServer)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not marshal JCRadius object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not marshal JCRadius object, err='%w'", err)
 	}
 
 	url := "/radiusservers"
@@ -78,16 +87,16 @@ func (jc JCAPI) AddUpdateRadiusServer(op JCOp, radiusServer JCRadiusServer) (id
 		url += "/" + radiusServer.Id
 	}
 
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(op), url, data)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(op), url, data)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not post new JCIDSource object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not post new JCIDSource object, err='%w'", err)
 	}
 
 	var resultES JCRadiusServer
 
-	err = json.Unmarshal(buffer, &resultES)
+	err = decodeJSON(buffer, &resultES)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
+		return "", fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 	}
 
 	if resultES.Name != radiusServer.Name {
@@ -98,9 +107,13 @@ func (jc JCAPI) AddUpdateRadiusServer(op JCOp, radiusServer JCRadiusServer) (id
 }
 
 func (jc JCAPI) DeleteRadiusServer(radiusServer JCRadiusServer) JCError {
-	_, err := jc.Delete(fmt.Sprintf("%s/%s", RADIUS_SERVERS_PATH, radiusServer.Id))
+	return jc.DeleteRadiusServerContext(context.Background(), radiusServer)
+}
+
+func (jc JCAPI) DeleteRadiusServerContext(ctx context.Context, radiusServer JCRadiusServer) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("%s/%s", RADIUS_SERVERS_PATH, radiusServer.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete ID source ID '%s': err='%s'", radiusServer.Id, err)
+		return fmt.Errorf("ERROR: Could not delete ID source ID '%s': err='%w'", radiusServer.Id, err)
 	}
 
 	return nil
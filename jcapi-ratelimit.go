@@ -0,0 +1,149 @@
+package jcapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// A 429 response can slow the limiter down to this fraction of its configured rate, but no further
+	minRateFraction float64 = 1.0 / 16
+	// Each successful response wins back this fraction of the configured rate
+	rateRecoveryFraction float64 = 1.0 / 10
+)
+
+//
+// RateLimiter is a token bucket that every request made through a JCAPI object waits
+// on before it is sent. A single RateLimiter is safe for use by multiple goroutines,
+// and can be shared by several JCAPI objects that use the same API key.
+//
+// The limiter adapts to the server: a 429 response halves the rate and pauses all
+// requests for the Retry-After delay, and successful responses then gradually bring
+// the rate back to its configured value.
+//
+type RateLimiter struct {
+	mu sync.Mutex
+
+	limit       float64 // configured requests per second
+	rate        float64 // current requests per second, lowered on 429 responses
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond on average, with bursts of up to burst requests.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		limit:  requestsPerSecond,
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// WithRateLimiter makes every request, including retries and AuthUser(), wait on limiter.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(cfg *clientConfig) {
+		cfg.rateLimiter = limiter
+	}
+}
+
+// Rate returns the number of requests per second currently allowed.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// Wait blocks until a request may be sent, or until ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve(time.Now())
+		if wait == 0 {
+			return nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long to wait before trying again
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	if l.rate <= 0 {
+		// A zero rate never hands out tokens beyond the burst, check back later
+		return time.Second
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+
+	l.last = now
+}
+
+// throttled is called on a 429 response
+func (l *RateLimiter) throttled(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	l.rate /= 2
+	if floor := l.limit * minRateFraction; l.rate < floor {
+		l.rate = floor
+	}
+
+	// Nobody gets to burst straight back into the rate limit
+	l.tokens = 0
+
+	if until := now.Add(retryAfter); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// succeeded is called on every response that was not rate limited
+func (l *RateLimiter) succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate < l.limit {
+		l.refill(time.Now())
+
+		l.rate += l.limit * rateRecoveryFraction
+		if l.rate > l.limit {
+			l.rate = l.limit
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package jcapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSharedAcrossGoroutines(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	defer ts.Close()
+
+	// 2 requests right away, then one every 20ms
+	limiter := NewRateLimiter(50, 2)
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRateLimiter(limiter))
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jc.DoBytes(MapJCOpToHTTP(Read), TAGS_PATH, nil); err != nil {
+				t.Errorf("DoBytes() failed, err='%s'", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Fatalf("6 requests at 50/s with a burst of 2 should take at least 80ms, took %s", elapsed)
+	}
+
+	if calls != 6 {
+		t.Fatalf("Expected 6 requests, got %d", calls)
+	}
+}
+
+func TestRateLimiterAdaptsTo429(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(100, 10)
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRateLimiter(limiter),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+
+	if _, err := jc.DoBytes(MapJCOpToHTTP(Read), TAGS_PATH, nil); err != nil {
+		t.Fatalf("DoBytes() failed, err='%s'", err)
+	}
+
+	// Halved on the 429, then won back 10% of the limit on the retry's success
+	if rate := limiter.Rate(); rate != 60 {
+		t.Fatalf("Expected the rate to drop to 60/s, got %f", rate)
+	}
+
+	for i := 0; i < 10; i++ {
+		limiter.succeeded()
+	}
+
+	if rate := limiter.Rate(); rate != 100 {
+		t.Fatalf("Expected the rate to recover to 100/s, got %f", rate)
+	}
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("The first request should use the burst, err='%s'", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the wait to be cut short by the context, err='%v'", err)
+	}
+}
@@ -0,0 +1,74 @@
+package jcapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+const (
+	rateLimitRemainingHeader string = "X-RateLimit-Remaining"
+	rateLimitLimitHeader     string = "X-RateLimit-Limit"
+)
+
+// ResponseMeta describes a response received from JumpCloud
+type ResponseMeta struct {
+	StatusCode         int
+	Status             string
+	Header             http.Header
+	RequestID          string
+	RateLimitLimit     int // -1 when JumpCloud did not report it
+	RateLimitRemaining int // -1 when JumpCloud did not report it
+}
+
+func newResponseMeta(resp *http.Response) *ResponseMeta {
+	return &ResponseMeta{
+		StatusCode:         resp.StatusCode,
+		Status:             resp.Status,
+		Header:             resp.Header,
+		RequestID:          resp.Header.Get(requestIDHeader),
+		RateLimitLimit:     headerInt(resp.Header, rateLimitLimitHeader),
+		RateLimitRemaining: headerInt(resp.Header, rateLimitRemainingHeader),
+	}
+}
+
+func headerInt(header http.Header, name string) int {
+	value, err := strconv.Atoi(header.Get(name))
+	if err != nil {
+		return -1
+	}
+
+	return value
+}
+
+// WithResponseHook calls hook with the metadata of every response received, including
+// the ones that are retried, so that tools can log them or watch the rate limit.
+func WithResponseHook(hook func(*ResponseMeta)) Option {
+	return func(cfg *clientConfig) {
+		cfg.responseHook = hook
+	}
+}
+
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode <= 299
+}
+
+//
+// DoBytesWithMeta works like DoBytes(), and also returns the metadata of the final
+// response. The metadata is returned with error responses as well, whenever JumpCloud
+// answered at all.
+//
+func (jc JCAPI) DoBytesWithMeta(op, urlQuery string, data []byte) ([]byte, *ResponseMeta, JCError) {
+	return jc.DoBytesWithMetaContext(context.Background(), op, urlQuery, data)
+}
+
+func (jc JCAPI) DoBytesWithMetaContext(ctx context.Context, op, urlQuery string, data []byte) ([]byte, *ResponseMeta, JCError) {
+	var meta *ResponseMeta
+
+	buffer, err := jc.doBytes(ctx, op, urlQuery, data, &meta)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	return buffer, meta, nil
+}
@@ -0,0 +1,79 @@
+package jcapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptAll2xxResponses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case TAGS_PATH:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"_id":"1234","name":"new tag"}`))
+		case TAGS_PATH + "/1234":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	tagId, err := jc.AddUpdateTag(Insert, JCTag{Name: "new tag"})
+	if err != nil {
+		t.Fatalf("A 201 Created response should not be an error, err='%s'", err)
+	}
+	if tagId != "1234" {
+		t.Fatalf("Expected tag ID '1234', got '%s'", tagId)
+	}
+
+	err = jc.DeleteTag(JCTag{Id: "1234"})
+	if err != nil {
+		t.Fatalf("A 204 No Content response should not be an error, err='%s'", err)
+	}
+}
+
+func TestStatusCodeIgnoresReasonPhrase(t *testing.T) {
+	rt := &recordingTransport{reason: "Everything Is Fine", body: `{"results":[]}`}
+
+	jc, _ := NewJCAPIWithOptions("fake-key", "https://jumpcloud.test/api", WithTransport(rt))
+
+	// A proxy rewriting the reason phrase must not turn a 200 into a failure
+	_, err := jc.DoBytes(MapJCOpToHTTP(Read), TAGS_PATH, nil)
+	if err != nil {
+		t.Fatalf("Expected the response to be accepted, err='%s'", err)
+	}
+}
+
+func TestResponseMeta(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-42")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "99")
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	defer ts.Close()
+
+	var hooked []*ResponseMeta
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithResponseHook(func(meta *ResponseMeta) {
+		hooked = append(hooked, meta)
+	}))
+
+	buffer, meta, err := jc.DoBytesWithMeta(MapJCOpToHTTP(Read), SYSTEMS_PATH, nil)
+	if err != nil {
+		t.Fatalf("DoBytesWithMeta() failed, err='%s'", err)
+	}
+
+	if string(buffer) != `{"results":[]}` {
+		t.Fatalf("Unexpected body '%s'", buffer)
+	}
+
+	if meta.StatusCode != http.StatusOK || meta.RequestID != "req-42" || meta.RateLimitLimit != 100 || meta.RateLimitRemaining != 99 {
+		t.Fatalf("Unexpected response metadata: %+v", meta)
+	}
+
+	if len(hooked) != 1 || hooked[0].RateLimitRemaining != 99 {
+		t.Fatalf("Expected the response hook to be called once, got %d calls", len(hooked))
+	}
+}
@@ -1,10 +1,9 @@
 package jcapi
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 )
 
 const (
@@ -23,6 +22,10 @@ func (e JCRestAuth) ToString() string {
 }
 
 func (jc JCAPI) AuthUser(username, password, tag string) (userAuthenticated bool, err error) {
+	return jc.AuthUserContext(context.Background(), username, password, tag)
+}
+
+func (jc JCAPI) AuthUserContext(ctx context.Context, username, password, tag string) (userAuthenticated bool, err error) {
 	userAuthenticated = false
 
 	auth := JCRestAuth{
@@ -33,28 +36,17 @@ func (jc JCAPI) AuthUser(username, password, tag string) (userAuthenticated bool
 
 	data, err := json.Marshal(auth)
 	if err != nil {
-		return false, fmt.Errorf("ERROR: Could not marshal the authentication request, err='%s'", err.Error())
-	}
-
-	client := &http.Client{}
-
-	req, err := http.NewRequest("POST", jc.UrlBase+AUTHENTICATE_PATH, bytes.NewReader(data))
-	if err != nil {
-		err = fmt.Errorf("ERROR: Could not build POST request: '%s'", err.Error())
-		return
+		return false, fmt.Errorf("ERROR: Could not marshal the authentication request, err='%w'", err)
 	}
 
-	jc.setHeader(req)
-
-	resp, err := client.Do(req)
+	resp, err := jc.send(ctx, MapJCOpToHTTP(Insert), AUTHENTICATE_PATH, data)
 	if err != nil {
-		err = fmt.Errorf("ERROR: client.Do() failed, err='%s'", err.Error())
 		return
 	}
 
 	defer resp.Body.Close()
 
-	if resp.Status == "200 OK" {
+	if isSuccessStatus(resp.StatusCode) {
 		userAuthenticated = true
 	}
 
@@ -0,0 +1,162 @@
+package jcapi
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// RetryPolicy controls how the core request path retries rate-limited and
+// transiently failing requests.
+//
+// Requests that are not idempotent (POSTs such as /runCommand) are only retried
+// when JumpCloud tells us it did not process them (429 Too Many Requests), unless
+// RetryNonIdempotent is set.
+//
+type RetryPolicy struct {
+	MaxAttempts        int           // total attempts, including the first one (<= 1 disables retries)
+	InitialBackoff     time.Duration // wait before the first retry
+	MaxBackoff         time.Duration // upper bound for the computed backoff
+	Multiplier         float64       // growth factor of the backoff between attempts
+	Jitter             float64       // fraction (0-1) of the backoff that is randomized
+	RetryNonIdempotent bool          // also retry POSTs on 5xx and network errors
+
+	OnRetry func(RetryAttempt) // called before sleeping ahead of each retry, may be nil
+}
+
+// RetryAttempt describes a failed attempt that is about to be retried
+type RetryAttempt struct {
+	Method     string
+	Path       string
+	Attempt    int           // the attempt that just failed, starting at 1
+	StatusCode int           // 0 when the request failed without a response
+	Err        error         // the network error, if any
+	Wait       time.Duration // how long we will wait before the next attempt
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy on the JCAPI object.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *clientConfig) {
+		cfg.retryPolicy = &policy
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+//
+// isIdempotent reports whether sending the request twice has the same effect as
+// sending it once. POSTs to the /search endpoints only read data, so they qualify.
+//
+func isIdempotent(method, urlQuery string) bool {
+	if method != http.MethodPost {
+		return true
+	}
+
+	return strings.HasPrefix(urlQuery, "/search/")
+}
+
+func (policy RetryPolicy) shouldRetry(method, urlQuery string, attempt int, resp *http.Response, err error) bool {
+	if attempt >= policy.MaxAttempts {
+		return false
+	}
+
+	// A 429 means the request was turned away before being processed, it's always safe to send again
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if !policy.RetryNonIdempotent && !isIdempotent(method, urlQuery) {
+		return false
+	}
+
+	if resp != nil {
+		return isRetryableStatus(resp.StatusCode)
+	}
+
+	return err != nil
+}
+
+// backoff returns how long to wait after the given (1-based) failed attempt
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := float64(policy.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if policy.MaxBackoff > 0 && wait > float64(policy.MaxBackoff) {
+		wait = float64(policy.MaxBackoff)
+	}
+
+	if policy.Jitter > 0 {
+		wait += (rand.Float64()*2 - 1) * policy.Jitter * wait
+	}
+
+	return time.Duration(wait)
+}
+
+//
+// retryAfter parses the Retry-After header, which holds either a number of
+// seconds or an HTTP date.
+//
+func retryAfter(resp *http.Response) (wait time.Duration, ok bool) {
+	if resp == nil {
+		return
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return
+}
+
+// sleepContext waits for d, returning early with the context's error if it is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
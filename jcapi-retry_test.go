@@ -0,0 +1,143 @@
+package jcapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy(retries *[]RetryAttempt) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+		OnRetry: func(retry RetryAttempt) {
+			*retries = append(*retries, retry)
+		},
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	defer ts.Close()
+
+	var retries []RetryAttempt
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRetryPolicy(fastRetryPolicy(&retries)))
+
+	_, err := jc.DoBytes(MapJCOpToHTTP(Read), SYSTEMS_PATH, nil)
+	if err != nil {
+		t.Fatalf("Expected the request to succeed after retrying, err='%s'", err)
+	}
+
+	if len(retries) != 2 {
+		t.Fatalf("Expected 2 retries to be reported, got %d", len(retries))
+	}
+
+	for i, retry := range retries {
+		if retry.Attempt != i+1 || retry.StatusCode != http.StatusServiceUnavailable || retry.Method != "GET" || retry.Path != SYSTEMS_PATH {
+			t.Fatalf("Unexpected retry report %d: %+v", i, retry)
+		}
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	var retries []RetryAttempt
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRetryPolicy(fastRetryPolicy(&retries)))
+
+	_, err := jc.DoBytes(MapJCOpToHTTP(Read), SYSTEMS_PATH, nil)
+	if err == nil {
+		t.Fatalf("Expected an error after exhausting all attempts")
+	}
+
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryDoesNotRepeatNonIdempotentPosts(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	var retries []RetryAttempt
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRetryPolicy(fastRetryPolicy(&retries)))
+
+	err := jc.RunCommand(JCCommand{Name: "test"})
+	if err == nil {
+		t.Fatalf("Expected RunCommand() to fail")
+	}
+
+	if calls != 1 || len(retries) != 0 {
+		t.Fatalf("A POST to %s must not be retried on a 503, got %d calls", RUN_COMMAND_PATH, calls)
+	}
+}
+
+func TestRetryHonorsRetryAfterOnPost(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"_id":"1234","name":"test"}`))
+	}))
+	defer ts.Close()
+
+	var retries []RetryAttempt
+	policy := fastRetryPolicy(&retries)
+
+	jc, _ := NewJCAPIWithOptions("fake-key", ts.URL, WithRetryPolicy(policy))
+
+	err := jc.RunCommand(JCCommand{Name: "test"})
+	if err != nil {
+		t.Fatalf("Expected RunCommand() to succeed after a 429, err='%s'", err)
+	}
+
+	if len(retries) != 1 || retries[0].Wait != time.Second {
+		t.Fatalf("Expected a single retry waiting for the Retry-After delay, got %+v", retries)
+	}
+}
+
+func TestRetryBackoffBounds(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.1}
+
+	for attempt := 1; attempt < 10; attempt++ {
+		expected := 100 * time.Millisecond << uint(attempt-1)
+		if expected > time.Second {
+			expected = time.Second
+		}
+
+		for i := 0; i < 20; i++ {
+			wait := policy.backoff(attempt)
+			if wait < expected*9/10 || wait > expected*11/10 {
+				t.Fatalf("Backoff for attempt %d is %s, expected %s +/- 10%%", attempt, wait, expected)
+			}
+		}
+	}
+}
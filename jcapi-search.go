@@ -0,0 +1,286 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	SEARCH_SYSTEMUSERS_PATH string = "/search/systemusers"
+	SEARCH_SYSTEMS_PATH     string = "/search/systems"
+)
+
+//
+// Filter is a condition of a search query, built with Eq(), In(), Regex() and friends,
+// or a combination of conditions built with And() and Or(). The zero Filter matches
+// everything. A filter built without a field name is invalid: see Err().
+//
+type Filter struct {
+	field    string
+	operator string // "" for an equality test
+	value    interface{}
+
+	combinator string // "and" or "or" for a combination of filters
+	filters    []Filter
+
+	err error // why the filter is invalid
+}
+
+func newFilter(field, operator string, value interface{}) Filter {
+	if field == "" {
+		return Filter{err: fmt.Errorf("ERROR: A search filter needs a field name, got '' with value %#v", value)}
+	}
+
+	return Filter{field: field, operator: operator, value: value}
+}
+
+// Eq matches the objects whose field is equal to value.
+func Eq(field string, value interface{}) Filter {
+	return newFilter(field, "", value)
+}
+
+// Ne matches the objects whose field is not equal to value.
+func Ne(field string, value interface{}) Filter {
+	return newFilter(field, "$ne", value)
+}
+
+// In matches the objects whose field is equal to any of values.
+func In(field string, values ...interface{}) Filter {
+	if values == nil {
+		values = []interface{}{}
+	}
+
+	return newFilter(field, "$in", values)
+}
+
+// Regex matches the objects whose field matches the regular expression pattern.
+func Regex(field string, pattern string) Filter {
+	return newFilter(field, "$regex", pattern)
+}
+
+// Gt matches the objects whose field is greater than value.
+func Gt(field string, value interface{}) Filter {
+	return newFilter(field, "$gt", value)
+}
+
+// Gte matches the objects whose field is greater than or equal to value.
+func Gte(field string, value interface{}) Filter {
+	return newFilter(field, "$gte", value)
+}
+
+// Lt matches the objects whose field is less than value.
+func Lt(field string, value interface{}) Filter {
+	return newFilter(field, "$lt", value)
+}
+
+// Lte matches the objects whose field is less than or equal to value.
+func Lte(field string, value interface{}) Filter {
+	return newFilter(field, "$lte", value)
+}
+
+// Exists matches the objects that have (or don't have) field set.
+func Exists(field string, exists bool) Filter {
+	return newFilter(field, "$exists", exists)
+}
+
+// Missing matches the objects whose field is not set, or set to null.
+func Missing(field string) Filter {
+	return newFilter(field, "", nil)
+}
+
+// And matches the objects that match every one of filters.
+func And(filters ...Filter) Filter {
+	return combine("and", filters)
+}
+
+// Or matches the objects that match at least one of filters, so everything when one of them is zero.
+func Or(filters ...Filter) Filter {
+	return combine("or", filters)
+}
+
+func combine(combinator string, filters []Filter) Filter {
+	var nonZero []Filter
+
+	matchesAll := false
+
+	for _, filter := range filters {
+		switch {
+		case filter.err != nil:
+			return Filter{err: filter.err}
+		case filter.IsZero():
+			// Everything or anything else is everything, while everything and x is x
+			matchesAll = matchesAll || combinator == "or"
+		default:
+			nonZero = append(nonZero, filter)
+		}
+	}
+
+	switch {
+	case matchesAll || len(nonZero) == 0:
+		return Filter{}
+	case len(nonZero) == 1:
+		return nonZero[0]
+	}
+
+	return Filter{combinator: combinator, filters: nonZero}
+}
+
+// IsZero reports whether the filter matches everything.
+func (f Filter) IsZero() bool {
+	return f.field == "" && f.combinator == "" && f.err == nil
+}
+
+// Err returns why the filter, or one of the filters it combines, is invalid, nil when it is valid.
+func (f Filter) Err() error {
+	return f.err
+}
+
+func (f Filter) MarshalJSON() ([]byte, error) {
+	switch {
+	case f.err != nil:
+		return nil, f.err
+	case f.combinator != "":
+		return json.Marshal(map[string][]Filter{f.combinator: f.filters})
+	case f.field == "":
+		return []byte("{}"), nil
+	case f.operator != "":
+		return json.Marshal(map[string]map[string]interface{}{f.field: {f.operator: f.value}})
+	}
+
+	return json.Marshal(map[string]interface{}{f.field: f.value})
+}
+
+//
+// SearchQuery describes a search on one of the JumpCloud /search endpoints. The filter
+// and the projection are sent in the body of the request, the paging parameters in its
+// URL, so that the whole result set can be walked with a Pager.
+//
+type SearchQuery struct {
+	Filter Filter   // the zero Filter matches every object
+	Fields []string // return only these fields of each object, all of them when empty
+
+	SearchTerm   string   // free text search, on SearchFields
+	SearchFields []string // the fields SearchTerm is looked for in
+
+	Sort     string // field to sort on, prefix with '-' for descending order
+	Skip     int    // number of matching objects to skip
+	Limit    int    // stop after this many objects, 0 for all of them
+	PageSize int    // objects requested per page, defaults to 100
+}
+
+type searchTermFilter struct {
+	SearchTerm string   `json:"searchTerm"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+type searchBody struct {
+	Filter       []Filter          `json:"filter,omitempty"`
+	Fields       string            `json:"fields,omitempty"`
+	SearchFilter *searchTermFilter `json:"searchFilter,omitempty"`
+}
+
+//
+// Body returns the JSON document sent to the search endpoint. The filter is sent as an
+// array of conditions that must all match, with any And() at the top level flattened
+// into it.
+//
+func (q SearchQuery) Body() ([]byte, error) {
+	if err := q.Filter.Err(); err != nil {
+		return nil, err
+	}
+
+	body := searchBody{
+		Fields: strings.Join(q.Fields, " "),
+	}
+
+	switch {
+	case q.Filter.combinator == "and":
+		body.Filter = q.Filter.filters
+	case !q.Filter.IsZero():
+		body.Filter = []Filter{q.Filter}
+	}
+
+	if q.SearchTerm != "" {
+		body.SearchFilter = &searchTermFilter{SearchTerm: q.SearchTerm, Fields: q.SearchFields}
+	}
+
+	return json.Marshal(body)
+}
+
+//
+// NewSearchPager returns a Pager over the results of q on the search endpoint at
+// searchPath, such as SEARCH_SYSTEMS_PATH or "/search/tags".
+//
+func (jc JCAPI) NewSearchPager(ctx context.Context, searchPath string, q SearchQuery) (*Pager, JCError) {
+	body, err := q.Body()
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not marshal search query, err='%w'", err)
+	}
+
+	pager := jc.NewPager(ctx, searchPath, PageOptions{PageSize: q.PageSize, Sort: q.Sort, Limit: q.Limit})
+	pager.method = MapJCOpToHTTP(Insert)
+	pager.body = body
+	pager.skip = q.Skip
+
+	return pager, nil
+}
+
+func (jc JCAPI) SearchSystemUsers(q SearchQuery) ([]JCUser, JCError) {
+	return jc.SearchSystemUsersContext(context.Background(), q)
+}
+
+// SearchSystemUsersContext returns every system user matching q.
+func (jc JCAPI) SearchSystemUsersContext(ctx context.Context, q SearchQuery) (userList []JCUser, err JCError) {
+	pager, err := jc.NewSearchPager(ctx, SEARCH_SYSTEMUSERS_PATH, q)
+	if err != nil {
+		return nil, err
+	}
+
+	for pager.Next() {
+		var user JCUser
+
+		err = pager.Decode(&user)
+		if err != nil {
+			return nil, err
+		}
+
+		userList = append(userList, user)
+	}
+
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%w'", pager.Err())
+	}
+
+	return
+}
+
+func (jc JCAPI) SearchSystems(q SearchQuery) ([]JCSystem, JCError) {
+	return jc.SearchSystemsContext(context.Background(), q)
+}
+
+// SearchSystemsContext returns every system matching q.
+func (jc JCAPI) SearchSystemsContext(ctx context.Context, q SearchQuery) (systems []JCSystem, err JCError) {
+	pager, err := jc.NewSearchPager(ctx, SEARCH_SYSTEMS_PATH, q)
+	if err != nil {
+		return nil, err
+	}
+
+	for pager.Next() {
+		var system JCSystem
+
+		err = pager.Decode(&system)
+		if err != nil {
+			return nil, err
+		}
+
+		systems = append(systems, system)
+	}
+
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%w'", pager.Err())
+	}
+
+	return
+}
@@ -0,0 +1,185 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestSearchQueryBody(t *testing.T) {
+	tests := []struct {
+		query SearchQuery
+		body  string
+	}{
+		{SearchQuery{}, `{}`},
+		{SearchQuery{Filter: Eq("email", "a@example.com")}, `{"filter":[{"email":"a@example.com"}]}`},
+		{SearchQuery{Filter: Eq("email", `a"},{"sudo":true`)}, `{"filter":[{"email":"a\"},{\"sudo\":true"}]}`},
+		{SearchQuery{Filter: And(Eq("os", "Ubuntu"), Eq("active", true)), Fields: []string{"hostname", "os"}},
+			`{"filter":[{"os":"Ubuntu"},{"active":true}],"fields":"hostname os"}`},
+		{SearchQuery{Filter: Or(Regex("hostname", `^web-\d+$`), In("arch", "x86_64", "arm64"))},
+			`{"filter":[{"or":[{"hostname":{"$regex":"^web-\\d+$"}},{"arch":{"$in":["x86_64","arm64"]}}]}]}`},
+		{SearchQuery{Filter: And(Ne("sudo", true), Or(Gte("unix_uid", 1000), Exists("external_dn", false)))},
+			`{"filter":[{"sudo":{"$ne":true}},{"or":[{"unix_uid":{"$gte":1000}},{"external_dn":{"$exists":false}}]}]}`},
+		{SearchQuery{Filter: And(Filter{}, Or(), Lt("lastContact", "2016-01-01T00:00:00Z"))},
+			`{"filter":[{"lastContact":{"$lt":"2016-01-01T00:00:00Z"}}]}`},
+		{SearchQuery{Filter: And(Eq("os", "Ubuntu"), Or(Eq("arch", "x86"), Filter{}))}, `{"filter":[{"os":"Ubuntu"}]}`},
+		{SearchQuery{SearchTerm: "jo<hn>", SearchFields: []string{"username", "email"}},
+			`{"searchFilter":{"searchTerm":"jo\u003chn\u003e","fields":["username","email"]}}`},
+	}
+
+	for _, test := range tests {
+		body, err := test.query.Body()
+		if err != nil {
+			t.Fatalf("Body() failed, err='%s'", err)
+		}
+
+		if string(body) != test.body {
+			t.Errorf("Unexpected body:\n got '%s'\nwant '%s'", body, test.body)
+		}
+
+		if !json.Valid(body) {
+			t.Errorf("Body '%s' is not valid JSON", body)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	if f := Or(Eq("os", "Ubuntu"), Filter{}); !f.IsZero() {
+		t.Fatalf("x or everything should match everything, got %+v", f)
+	}
+
+	for _, f := range []Filter{Eq("", "a"), In("", 1), And(Eq("os", "Ubuntu"), Or(Regex("", "x"), Filter{}))} {
+		if f.Err() == nil || f.IsZero() {
+			t.Errorf("Expected %+v to be invalid", f)
+		}
+
+		if _, err := (SearchQuery{Filter: f}).Body(); err == nil {
+			t.Errorf("Expected Body() to fail for %+v", f)
+		}
+	}
+}
+
+func TestSearchSystemUsersPages(t *testing.T) {
+	var urls, bodies []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if r.Method != http.MethodPost || r.URL.Path != SEARCH_SYSTEMUSERS_PATH {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		urls = append(urls, r.URL.RequestURI())
+		bodies = append(bodies, string(body))
+
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var results []string
+		for i := skip; i < 25 && i < skip+limit; i++ {
+			results = append(results, fmt.Sprintf(`{"_id":"id%d","username":"user%d"}`, i, i))
+		}
+
+		fmt.Fprintf(w, `{"totalCount":25,"results":[%s]}`, strings.Join(results, ","))
+	}))
+	defer ts.Close()
+
+	query := SearchQuery{
+		Filter:   Regex("username", "^user"),
+		Sort:     "-username",
+		Skip:     5,
+		Limit:    12,
+		PageSize: 5,
+	}
+
+	users, err := NewJCAPI("key", ts.URL).SearchSystemUsers(query)
+	if err != nil {
+		t.Fatalf("SearchSystemUsers() failed, err='%s'", err)
+	}
+
+	if len(users) != 12 || users[0].Id != "id5" || users[11].Id != "id16" {
+		t.Fatalf("Unexpected users returned: %v", users)
+	}
+
+	expected := []string{
+		"/search/systemusers?skip=5&limit=5&sort=-username",
+		"/search/systemusers?skip=10&limit=5&sort=-username",
+		"/search/systemusers?skip=15&limit=2&sort=-username",
+	}
+
+	if strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Fatalf("Unexpected requests: %v", urls)
+	}
+
+	for _, body := range bodies {
+		if body != `{"filter":[{"username":{"$regex":"^user"}}]}` {
+			t.Fatalf("Unexpected body '%s'", body)
+		}
+	}
+}
+
+func TestGetSystemByHostNameSearches(t *testing.T) {
+	rt := &recordingTransport{body: `{"totalCount":1,"results":[{"_id":"a","hostname":"web\"1"}]}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	systems, err := jc.GetSystemByHostName(`web"1`, false)
+	if err != nil {
+		t.Fatalf("GetSystemByHostName() failed, err='%s'", err)
+	}
+
+	if len(systems) != 1 || systems[0].Hostname != `web"1` {
+		t.Fatalf("Unexpected systems: %v", systems)
+	}
+
+	body, _ := ioutil.ReadAll(rt.requests[0].Body)
+	if string(body) != `{"filter":[{"hostname":"web\"1"}]}` || rt.requests[0].URL.Path != "/api"+SEARCH_SYSTEMS_PATH {
+		t.Fatalf("Unexpected request %s '%s'", rt.requests[0].URL, body)
+	}
+}
+
+func TestSearchQueryBodyRoundTrip(t *testing.T) {
+	roundTrip := func(field, value, pattern adversarialString) bool {
+		query := SearchQuery{Filter: Or(Eq(string(field), string(value)), Regex("name", string(pattern)))}
+
+		body, err := query.Body()
+
+		if field == "" {
+			// A filter without a field is an error, rather than a filter matching everything
+			return err != nil
+		}
+
+		if err != nil || !json.Valid(body) {
+			t.Logf("Invalid JSON '%s', err='%v'", body, err)
+			return false
+		}
+
+		var decoded struct {
+			Filter []struct {
+				Or []map[string]interface{} `json:"or"`
+			} `json:"filter"`
+		}
+
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return false
+		}
+
+		or := decoded.Filter[0].Or
+		regex, _ := or[1]["name"].(map[string]interface{})
+
+		return len(or) == 2 && or[0][string(field)] == string(value) && regex["$regex"] == string(pattern)
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
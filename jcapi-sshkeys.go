@@ -0,0 +1,400 @@
+package jcapi
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+//
+// JCUserSSHKey is a public key the JumpCloud agent adds to the authorized_keys of the
+// user on their systems
+//
+type JCUserSSHKey struct {
+	Id         string    `json:"_id,omitempty"`
+	Name       string    `json:"name"`
+	PublicKey  string    `json:"public_key"`
+	CreateDate Timestamp `json:"create_date,omitzero"`
+}
+
+//
+// SSHPublicKey is an SSH public key parsed and checked by ParseSSHPublicKey(), in the
+// format of the authorized_keys file.
+//
+type SSHPublicKey struct {
+	Type        string // such as "ssh-ed25519" or "ssh-rsa"
+	Bits        int    // the size of the key, as reported by ssh-keygen -l
+	Fingerprint string // the SHA256 fingerprint of the key, as reported by ssh-keygen -l
+	Comment     string
+	Options     string // the authorized_keys options before the key, if any
+	Blob        []byte // the key in the SSH wire format
+}
+
+// String returns the key in the authorized_keys format, without its options.
+func (key SSHPublicKey) String() string {
+	line := key.Type + " " + base64.StdEncoding.EncodeToString(key.Blob)
+
+	if key.Comment != "" {
+		line += " " + key.Comment
+	}
+
+	return line
+}
+
+// sshReader reads the fields of a key in the SSH wire format (RFC 4251)
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sshReader) readString() []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if len(r.data) < 4 || uint64(binary.BigEndian.Uint32(r.data)) > uint64(len(r.data)-4) {
+		r.err = fmt.Errorf("truncated key data")
+		return nil
+	}
+
+	length := binary.BigEndian.Uint32(r.data)
+	value := r.data[4 : 4+length]
+	r.data = r.data[4+length:]
+
+	return value
+}
+
+func (r *sshReader) readMPInt() *big.Int {
+	value := r.readString()
+
+	if len(value) > 0 && value[0]&0x80 != 0 {
+		r.err = fmt.Errorf("negative integer in key data")
+	}
+
+	return new(big.Int).SetBytes(value)
+}
+
+// sshKeyParsers checks the wire format of each type of key supported, and returns its size
+var sshKeyParsers = map[string]func(r *sshReader) (int, error){
+	"ssh-rsa": func(r *sshReader) (int, error) {
+		e, n := r.readMPInt(), r.readMPInt()
+		if r.err == nil && (e.Bit(0) == 0 || e.Cmp(big.NewInt(3)) < 0 || n.Sign() == 0) {
+			return 0, fmt.Errorf("invalid RSA key")
+		}
+
+		return n.BitLen(), nil
+	},
+	"ssh-dss": func(r *sshReader) (int, error) {
+		p := r.readMPInt()
+		r.readMPInt()
+		r.readMPInt()
+		r.readMPInt()
+
+		return p.BitLen(), nil
+	},
+	"ecdsa-sha2-nistp256":                readECDSAKey("nistp256", 256, false),
+	"ecdsa-sha2-nistp384":                readECDSAKey("nistp384", 384, false),
+	"ecdsa-sha2-nistp521":                readECDSAKey("nistp521", 521, false),
+	"sk-ecdsa-sha2-nistp256@openssh.com": readECDSAKey("nistp256", 256, true),
+	"ssh-ed25519":                        readEd25519Key(false),
+	"sk-ssh-ed25519@openssh.com":         readEd25519Key(true),
+}
+
+func readECDSAKey(curve string, bits int, securityKey bool) func(r *sshReader) (int, error) {
+	return func(r *sshReader) (int, error) {
+		name, point := r.readString(), r.readString()
+		if securityKey {
+			r.readString() // the application
+		}
+
+		// An uncompressed point: 0x04 and both coordinates
+		size := (bits + 7) / 8
+		if r.err == nil && (string(name) != curve || len(point) != 1+2*size || point[0] != 4) {
+			return 0, fmt.Errorf("invalid ECDSA key on curve '%s'", name)
+		}
+
+		return bits, nil
+	}
+}
+
+func readEd25519Key(securityKey bool) func(r *sshReader) (int, error) {
+	return func(r *sshReader) (int, error) {
+		key := r.readString()
+		if securityKey {
+			r.readString() // the application
+		}
+
+		if r.err == nil && len(key) != 32 {
+			return 0, fmt.Errorf("invalid Ed25519 key of %d bytes", len(key))
+		}
+
+		return 256, nil
+	}
+}
+
+//
+// splitSSHKeyOptions splits the authorized_keys options off the front of line, the
+// options being everything up to the first blank outside of double quotes.
+//
+func splitSSHKeyOptions(line string) (options, rest string) {
+	quoted := false
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				return line[:i], strings.TrimLeft(line[i:], " \t")
+			}
+		}
+	}
+
+	return line, ""
+}
+
+//
+// ParseSSHPublicKey parses one line of an authorized_keys file (or a .pub file), and
+// checks that the key it holds is well formed.
+//
+func ParseSSHPublicKey(line string) (key SSHPublicKey, err error) {
+	line = strings.TrimSpace(line)
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		if _, ok := sshKeyParsers[fields[0]]; !ok {
+			key.Options, line = splitSSHKeyOptions(line)
+			fields = strings.Fields(line)
+		}
+	}
+
+	if len(fields) < 2 {
+		return key, fmt.Errorf("ERROR: Not an SSH public key: '%s'", line)
+	}
+
+	parser, ok := sshKeyParsers[fields[0]]
+	if !ok {
+		return key, fmt.Errorf("ERROR: Unsupported SSH key type '%s'", fields[0])
+	}
+
+	key.Type = fields[0]
+	key.Comment = strings.Join(fields[2:], " ")
+
+	key.Blob, err = base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return key, fmt.Errorf("ERROR: Could not decode %s key, err='%w'", key.Type, err)
+	}
+
+	r := &sshReader{data: key.Blob}
+
+	if blobType := string(r.readString()); r.err == nil && blobType != key.Type {
+		return key, fmt.Errorf("ERROR: %s key holds a key of type '%s'", key.Type, blobType)
+	}
+
+	key.Bits, err = parser(r)
+	if err == nil && r.err == nil && len(r.data) > 0 {
+		err = fmt.Errorf("%d bytes of trailing data", len(r.data))
+	}
+	if err == nil {
+		err = r.err
+	}
+	if err != nil {
+		return key, fmt.Errorf("ERROR: Invalid %s key, err='%w'", key.Type, err)
+	}
+
+	sum := sha256.Sum256(key.Blob)
+	key.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+
+	return key, nil
+}
+
+// Parse parses and checks the public key.
+func (key JCUserSSHKey) Parse() (SSHPublicKey, error) {
+	return ParseSSHPublicKey(key.PublicKey)
+}
+
+func (jc JCAPI) GetUserSSHKeys(userId string) ([]JCUserSSHKey, JCError) {
+	return jc.GetUserSSHKeysContext(context.Background(), userId)
+}
+
+func (jc JCAPI) GetUserSSHKeysContext(ctx context.Context, userId string) (keys []JCUserSSHKey, err JCError) {
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), "/systemusers/"+userId+"/sshkeys", nil)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not get SSH keys of user ID '%s', err='%w'", userId, err)
+	}
+
+	err = decodeJSON(buffer, &keys)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
+	}
+
+	return
+}
+
+//
+// Add a public key to a user on JumpCloud. The key is checked before being sent, and is
+// named after its comment (or its fingerprint) unless key.Name is set.
+//
+func (jc JCAPI) AddUserSSHKey(userId string, key JCUserSSHKey) (JCUserSSHKey, JCError) {
+	return jc.AddUserSSHKeyContext(context.Background(), userId, key)
+}
+
+func (jc JCAPI) AddUserSSHKeyContext(ctx context.Context, userId string, key JCUserSSHKey) (newKey JCUserSSHKey, err JCError) {
+	parsed, err := key.Parse()
+	if err != nil {
+		return newKey, err
+	}
+
+	request := JCUserSSHKey{Name: key.Name, PublicKey: parsed.String()}
+	if request.Name == "" {
+		request.Name = parsed.Comment
+	}
+	if request.Name == "" {
+		request.Name = parsed.Fingerprint
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return newKey, fmt.Errorf("ERROR: Could not marshal JCUserSSHKey object, err='%w'", err)
+	}
+
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Insert), "/systemusers/"+userId+"/sshkeys", data)
+	if err != nil {
+		return newKey, fmt.Errorf("ERROR: Could not add SSH key '%s' to user ID '%s', err='%w'", request.Name, userId, err)
+	}
+
+	err = decodeJSON(buffer, &newKey)
+	if err != nil {
+		return newKey, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
+	}
+
+	return
+}
+
+func (jc JCAPI) DeleteUserSSHKey(userId string, key JCUserSSHKey) JCError {
+	return jc.DeleteUserSSHKeyContext(context.Background(), userId, key)
+}
+
+func (jc JCAPI) DeleteUserSSHKeyContext(ctx context.Context, userId string, key JCUserSSHKey) JCError {
+	if key.Id == "" {
+		return fmt.Errorf("ERROR: Cannot delete SSH key '%s' without its ID", key.Name)
+	}
+
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("/systemusers/%s/sshkeys/%s", userId, key.Id))
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not delete SSH key '%s' of user ID '%s': err='%w'", key.Name, userId, err)
+	}
+
+	return nil
+}
+
+// SSHKeySync lists the keys to add to a user, and to remove from them, to match an authorized_keys file
+type SSHKeySync struct {
+	Add    []JCUserSSHKey
+	Remove []JCUserSSHKey
+}
+
+//
+// PlanSSHKeySync compares the keys of a user with the keys of an authorized_keys file,
+// by fingerprint. Blank lines and comments are skipped, any other line that is not a
+// valid key fails the whole plan. The keys of the user that don't parse are removed.
+//
+func PlanSSHKeySync(current []JCUserSSHKey, authorizedKeys io.Reader) (plan SSHKeySync, err error) {
+	wanted := make(map[string]bool)
+
+	scanner := bufio.NewScanner(authorizedKeys)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var fileKeys []SSHPublicKey
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := ParseSSHPublicKey(line)
+		if err != nil {
+			return plan, fmt.Errorf("ERROR: Line %d of authorized_keys, err='%w'", lineNumber, err)
+		}
+
+		if !wanted[key.Fingerprint] {
+			wanted[key.Fingerprint] = true
+			fileKeys = append(fileKeys, key)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return plan, fmt.Errorf("ERROR: Could not read authorized_keys, err='%w'", err)
+	}
+
+	existing := make(map[string]bool)
+
+	for _, key := range current {
+		parsed, err := key.Parse()
+		if err != nil || !wanted[parsed.Fingerprint] || existing[parsed.Fingerprint] {
+			plan.Remove = append(plan.Remove, key)
+			continue
+		}
+
+		existing[parsed.Fingerprint] = true
+	}
+
+	for _, key := range fileKeys {
+		if !existing[key.Fingerprint] {
+			plan.Add = append(plan.Add, JCUserSSHKey{PublicKey: key.String()})
+		}
+	}
+
+	return
+}
+
+func (jc JCAPI) SyncUserSSHKeys(userId string, authorizedKeys io.Reader) (SSHKeySync, JCError) {
+	return jc.SyncUserSSHKeysContext(context.Background(), userId, authorizedKeys)
+}
+
+//
+// SyncUserSSHKeysContext makes the keys of the user match an authorized_keys file. The
+// new keys are added before the old ones are removed, so the user is never left without
+// a key. It returns the changes made, which are only part of the plan on error.
+//
+func (jc JCAPI) SyncUserSSHKeysContext(ctx context.Context, userId string, authorizedKeys io.Reader) (done SSHKeySync, err JCError) {
+	current, err := jc.GetUserSSHKeysContext(ctx, userId)
+	if err != nil {
+		return done, err
+	}
+
+	plan, err := PlanSSHKeySync(current, authorizedKeys)
+	if err != nil {
+		return done, err
+	}
+
+	for _, key := range plan.Add {
+		newKey, err := jc.AddUserSSHKeyContext(ctx, userId, key)
+		if err != nil {
+			return done, err
+		}
+
+		done.Add = append(done.Add, newKey)
+	}
+
+	for _, key := range plan.Remove {
+		err = jc.DeleteUserSSHKeyContext(ctx, userId, key)
+		if err != nil {
+			return done, err
+		}
+
+		done.Remove = append(done.Remove, key)
+	}
+
+	return
+}
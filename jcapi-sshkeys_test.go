@@ -0,0 +1,150 @@
+package jcapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Keys made with ssh-keygen, and what ssh-keygen -l says about them
+const (
+	testRSAKey     = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDbnk9q1LW631H+GubtwwDxz2TAhUK8Lzm3eeZ9cZMbjwHuALKU3XQ74u6jqIEpR18zEVb9QJcyWTxYPWb0sNi+9Uh1Btdxdz6WMDMeGEvipUVzeSy/yzFcryfsFH+qJoX8UVvsdCrmZepHABavqedDF7VsAH8SeZszIg/xaNCxhmQErf4dOnGq/8ZJZsaphOhG0PRtKpQG5hKCAermhAhAgGlt4eHqEj1rv5r5f/gEfoj9ZV3hrtCv/VB/CEdBLOqX/IwcTLx5YIiYm5ht9DhW9Lqcz/tncbFW4mBzdhFRcUrueysTup/RtoG88S4PauRqrBnRThSHuidfHVG3Z+Hn user@rsa"
+	testECDSAKey   = "ecdsa-sha2-nistp384 AAAAE2VjZHNhLXNoYTItbmlzdHAzODQAAAAIbmlzdHAzODQAAABhBNmNlYq3JrQ12F/Qgka0/M9hVb3VaC+xjL28HrCVnvdK5pMZYw7zE2FbFU4ZfCaaGPW6hyqd3+8tGL5ZOQeeGSrMp5G42k82BUv5NWkIhNcEWF703fhPJ56faYakCZI/Ag== user@ecdsa"
+	testEd25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMIb8XGg1fRcafZgno3nc6+kAbdCZQ5obDDTta82e/NC user@ed25519"
+	testDSAKey     = "ssh-dss AAAAB3NzaC1kc3MAAACBAOf2l9UwSpvTFjESMMZ8WSmVaVZpt6C3pvVHargv9vYQO3tXoHpZxO/j9YX4rcyLOr6F3CYLo/FY8vPph/REBvlLtHYuwAuKy8qXeUzAh0CnHNKBATinYOFZysCkx5JMT1EQ4cLz9Y6/jWfw0MfhkrSEisXnglpiFWysZ/q5KDRtAAAAFQCCkk5y3cgCzoNhcFTudoTjh58W9QAAAIEA5hGVHAa/CnTasX8gZPvLk+BXZ+epr1vSq44vXfW7TbpqW/0NxRsWUU0B7iu5sPqUJWf5FqQBFNsm2GgU9VFHfceT6X6MPCHKnw5LGy+YVMBHtDPjwxLRTt2/soWWJkrfYBUMRGl7+L8FSn7M3cY26Ub3qIMMovH+aaisiAgEGNYAAACBALL/lisBg+UC8Hcm5xRG0LXTl7JQXqYPguSFbxqjNnr1ugrxorQuFux8ysGCXOf4nDFOFtOblsEk3cZ+8lkNM1wEnfgtSMBqsY8oHya4MCd7HXwtBIca7CPIgzOcYpR3xmJfBLgkmQPLyxtOfIwZhFVmFuBuNPBB3tsC7jC8cLOf user@dsa"
+)
+
+func TestParseSSHPublicKey(t *testing.T) {
+	tests := []struct {
+		line        string
+		keyType     string
+		bits        int
+		fingerprint string
+		comment     string
+		options     string
+	}{
+		{testRSAKey, "ssh-rsa", 2048, "SHA256:WWFmIS330M8HcDe/Y/1/iyQZ8oh14PV18+UCHl5WWQA", "user@rsa", ""},
+		{testECDSAKey, "ecdsa-sha2-nistp384", 384, "SHA256:6VfRVrUluSHizpGxWwQuQOs9fx5RVefDNKvbNxTaiVY", "user@ecdsa", ""},
+		{testEd25519Key, "ssh-ed25519", 256, "SHA256:ecGe3vjoKJJwPWdCOwUHApIAgNg7TCGJEj7Enavp8Cg", "user@ed25519", ""},
+		{testDSAKey, "ssh-dss", 1024, "SHA256:vw3fhASnPFR+f/EJtYX9dLUgE21YSZUObPJLm9jcmao", "user@dsa", ""},
+		{`from="10.0.0.1,host name",no-pty  ` + testEd25519Key + " laptop", "ssh-ed25519", 256,
+			"SHA256:ecGe3vjoKJJwPWdCOwUHApIAgNg7TCGJEj7Enavp8Cg", "user@ed25519 laptop", `from="10.0.0.1,host name",no-pty`},
+	}
+
+	for _, test := range tests {
+		key, err := ParseSSHPublicKey(test.line)
+		if err != nil {
+			t.Fatalf("ParseSSHPublicKey('%s') failed, err='%s'", test.line, err)
+		}
+
+		if key.Type != test.keyType || key.Bits != test.bits || key.Fingerprint != test.fingerprint ||
+			key.Comment != test.comment || key.Options != test.options {
+			t.Errorf("Unexpected key parsed from '%s': %+v", test.line, key)
+		}
+
+		if !strings.HasPrefix(test.line, key.Options) || !strings.HasSuffix(test.line, key.String()) {
+			t.Errorf("Unexpected authorized_keys line '%s' for '%s'", key, test.line)
+		}
+	}
+}
+
+func TestParseSSHPublicKeyErrors(t *testing.T) {
+	fields := strings.Fields(testEd25519Key)
+	blob, _ := base64.StdEncoding.DecodeString(fields[1])
+
+	for _, line := range []string{
+		"",
+		"ssh-ed25519",
+		"ssh-foo AAAA",
+		"ssh-ed25519 not-base64!",
+		"ssh-rsa " + fields[1],
+		"ssh-ed25519 " + base64.StdEncoding.EncodeToString(blob[:len(blob)-1]),
+		"ssh-ed25519 " + base64.StdEncoding.EncodeToString(append(blob, 0)),
+		"ssh-ed25519 " + base64.StdEncoding.EncodeToString([]byte{0xff, 0xff, 0xff, 0xff}),
+	} {
+		if key, err := ParseSSHPublicKey(line); err == nil {
+			t.Errorf("Expected an error parsing '%s', got %+v", line, key)
+		}
+	}
+}
+
+func TestPlanSSHKeySync(t *testing.T) {
+	current := []JCUserSSHKey{
+		{Id: "k1", Name: "rsa", PublicKey: testRSAKey},
+		{Id: "k2", Name: "dsa", PublicKey: testDSAKey},
+		{Id: "k3", Name: "broken", PublicKey: "ssh-rsa AAAA"},
+		{Id: "k4", Name: "rsa again", PublicKey: strings.TrimSuffix(testRSAKey, " user@rsa")},
+	}
+
+	authorizedKeys := "# managed by hand\n\n" + testEd25519Key + "\n" +
+		"no-agent-forwarding " + testRSAKey + "\n" + testEd25519Key + " duplicate\n"
+
+	plan, err := PlanSSHKeySync(current, strings.NewReader(authorizedKeys))
+	if err != nil {
+		t.Fatalf("PlanSSHKeySync() failed, err='%s'", err)
+	}
+
+	if len(plan.Add) != 1 || plan.Add[0].PublicKey != testEd25519Key {
+		t.Fatalf("Unexpected keys to add: %v", plan.Add)
+	}
+
+	var removed []string
+	for _, key := range plan.Remove {
+		removed = append(removed, key.Id)
+	}
+
+	if strings.Join(removed, ",") != "k2,k3,k4" {
+		t.Fatalf("Unexpected keys to remove: %v", removed)
+	}
+
+	_, err = PlanSSHKeySync(current, strings.NewReader(testRSAKey+"\nssh-rsa garbage\n"))
+	if err == nil || !strings.Contains(err.Error(), "Line 2") {
+		t.Fatalf("Expected an error on line 2, got '%v'", err)
+	}
+}
+
+func TestSyncUserSSHKeys(t *testing.T) {
+	var requests []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]JCUserSSHKey{{Id: "k1", Name: "old", PublicKey: testDSAKey}})
+		case http.MethodPost:
+			var key JCUserSSHKey
+			json.Unmarshal(body, &key)
+
+			key.Id = fmt.Sprintf("new%d", len(requests))
+			json.NewEncoder(w).Encode(key)
+		}
+	}))
+	defer ts.Close()
+
+	done, err := NewJCAPI("key", ts.URL).SyncUserSSHKeys("a", strings.NewReader(testECDSAKey+"\n"+testRSAKey))
+	if err != nil {
+		t.Fatalf("SyncUserSSHKeys() failed, err='%s'", err)
+	}
+
+	expected := []string{
+		"GET /systemusers/a/sshkeys",
+		"POST /systemusers/a/sshkeys",
+		"POST /systemusers/a/sshkeys",
+		"DELETE /systemusers/a/sshkeys/k1",
+	}
+
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	if len(done.Add) != 2 || done.Add[0].Id != "new2" || done.Add[0].Name != "user@ecdsa" || len(done.Remove) != 1 {
+		t.Fatalf("Unexpected changes %+v", done)
+	}
+}
@@ -0,0 +1,230 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//
+// ErrStopStream can be returned by a Stream*() callback to end the stream early.
+// The Stream*() call then returns nil.
+//
+var ErrStopStream = errors.New("jcapi: stop stream")
+
+//
+// decodeResultsStream walks a page envelope ({"totalCount": N, "results": [...]}) token
+// by token, and calls decodeItem once for each element of the results array, with the
+// decoder positioned on that element and root set to its JSON path. Only one element
+// is held in memory at a time.
+//
+func decodeResultsStream(r io.Reader, decodeItem func(dec *json.Decoder, root string) error) (count int, totalCount int, err error) {
+	totalCount = -1
+
+	dec := json.NewDecoder(r)
+
+	// Failures to parse the envelope itself, errors from decodeItem are returned as they are
+	envelopeError := func(path string, err error) error {
+		return newDecodeError(nil, path, &JCPage{}, err)
+	}
+
+	if err = expectDelim(dec, '{'); err != nil {
+		return 0, totalCount, envelopeError("$", err)
+	}
+
+	for dec.More() {
+		var token json.Token
+
+		token, err = dec.Token()
+		if err != nil {
+			return count, totalCount, envelopeError("$", err)
+		}
+
+		switch token {
+		case "results":
+			token, err = dec.Token()
+			if err != nil {
+				return count, totalCount, envelopeError("$.results", err)
+			}
+
+			// "results": null is an empty page
+			if token == nil {
+				continue
+			}
+
+			if token != json.Delim('[') {
+				err = fmt.Errorf("expected '[' for results but found '%v'", token)
+				return count, totalCount, envelopeError("$.results", err)
+			}
+
+			for dec.More() {
+				if err = decodeItem(dec, fmt.Sprintf("$.results[%d]", count)); err != nil {
+					return
+				}
+				count++
+			}
+
+			if err = expectDelim(dec, ']'); err != nil {
+				return count, totalCount, envelopeError("$.results", err)
+			}
+		case "totalCount":
+			if err = dec.Decode(&totalCount); err != nil {
+				return count, totalCount, envelopeError("$.totalCount", err)
+			}
+		default:
+			// Skip over any other value in the envelope
+			var skipped json.RawMessage
+			if err = dec.Decode(&skipped); err != nil {
+				return count, totalCount, envelopeError("$"+formatPathKey(fmt.Sprint(token)), err)
+			}
+		}
+	}
+
+	if err = expectDelim(dec, '}'); err != nil {
+		return count, totalCount, envelopeError("$", err)
+	}
+
+	return
+}
+
+//
+// decodeStreamItem decodes the next value of dec, found at path root of the payload,
+// into v. The value is read in full first so that a DecodeError can quote it.
+//
+func decodeStreamItem(dec *json.Decoder, root string, v interface{}) error {
+	var raw json.RawMessage
+
+	if err := dec.Decode(&raw); err != nil {
+		return newDecodeError(nil, root, v, err)
+	}
+
+	return decodeJSONAt(raw, root, v)
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected '%s' but found '%v'", delim, token)
+	}
+
+	return nil
+}
+
+//
+// stream walks the same pages as Next() would, but decodes each page straight from
+// the response body and hands every element to decodeItem as it is parsed.
+//
+func (p *Pager) stream(decodeItem func(dec *json.Decoder, root string) error) error {
+	for !p.lastPage && !p.stopped {
+		if p.opts.Limit > 0 && p.seen >= p.opts.Limit {
+			return nil
+		}
+
+		// Stop between pages if the caller has given up on us
+		if p.ctx.Err() != nil {
+			return p.ctx.Err()
+		}
+
+		var count, totalCount int
+
+		err := p.jc.doStream(p.ctx, p.method, p.pageURL(), p.body, nil, func(body io.Reader) error {
+			var err error
+
+			count, totalCount, err = decodeResultsStream(body, func(dec *json.Decoder, root string) error {
+				if p.opts.Limit > 0 && p.seen >= p.opts.Limit {
+					return ErrStopStream
+				}
+
+				p.seen++
+				return decodeItem(dec, root)
+			})
+
+			return err
+		})
+
+		if errors.Is(err, ErrStopStream) {
+			p.stopped = true
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ERROR: Could not stream page at skip=%d from '%s', err='%w'", p.skip, p.path, err)
+		}
+
+		p.skip += count
+		if totalCount >= 0 {
+			p.totalCount = totalCount
+		}
+
+		p.lastPage = count < p.opts.PageSize || (p.totalCount >= 0 && p.skip >= p.totalCount)
+	}
+
+	return nil
+}
+
+//
+// StreamSystems calls fn with each system in the organization as soon as it has been
+// parsed, so memory use stays the same whatever the number of systems. Return
+// ErrStopStream from fn to stop early.
+//
+func (jc JCAPI) StreamSystems(ctx context.Context, opts PageOptions, fn func(JCSystem) error) JCError {
+	if opts.Sort == "" {
+		opts.Sort = "hostname"
+	}
+
+	return jc.NewPager(ctx, SYSTEMS_PATH, opts).stream(func(dec *json.Decoder, root string) error {
+		var system JCSystem
+
+		if err := decodeStreamItem(dec, root, &system); err != nil {
+			return err
+		}
+
+		return fn(system)
+	})
+}
+
+//
+// StreamSystemUsers calls fn with each system user as soon as it has been parsed.
+// The users are the records returned by the list endpoint, see GetSystemUserById() for
+// the full details of a user. Return ErrStopStream from fn to stop early.
+//
+func (jc JCAPI) StreamSystemUsers(ctx context.Context, opts PageOptions, fn func(JCUser) error) JCError {
+	if opts.Sort == "" {
+		opts.Sort = "username"
+	}
+
+	return jc.NewPager(ctx, "/systemusers", opts).stream(func(dec *json.Decoder, root string) error {
+		var user JCUser
+
+		if err := decodeStreamItem(dec, root, &user); err != nil {
+			return err
+		}
+
+		return fn(user)
+	})
+}
+
+//
+// StreamCommandResults calls fn with each command result as soon as it has been
+// parsed. Return ErrStopStream from fn to stop early.
+//
+func (jc JCAPI) StreamCommandResults(ctx context.Context, opts PageOptions, fn func(JCCommandResult) error) JCError {
+	if opts.Sort == "" {
+		opts.Sort = "-requestTime"
+	}
+
+	return jc.NewPager(ctx, COMMAND_RESULTS_PATH, opts).stream(func(dec *json.Decoder, root string) error {
+		var result JCCommandResult
+
+		if err := decodeStreamItem(dec, root, &result); err != nil {
+			return err
+		}
+
+		return fn(result)
+	})
+}
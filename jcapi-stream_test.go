@@ -0,0 +1,120 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeResultsStream(t *testing.T) {
+	body := `{"ignored":{"nested":[1,2,3]},"results":[{"_id":"a"},{"_id":"b"},{"_id":"c"}],"totalCount":3}`
+
+	var ids []string
+	count, totalCount, err := decodeResultsStream(strings.NewReader(body), func(dec *json.Decoder, root string) error {
+		var system JCSystem
+		if err := dec.Decode(&system); err != nil {
+			return err
+		}
+		ids = append(ids, system.Id)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("decodeResultsStream() failed, err='%s'", err)
+	}
+
+	if count != 3 || totalCount != 3 || strings.Join(ids, ",") != "a,b,c" {
+		t.Fatalf("Unexpected results: count=%d totalCount=%d ids=%v", count, totalCount, ids)
+	}
+
+	for _, bad := range []string{`[]`, `{"results":{}}`, `{"results":[{"_id":"a"}`, ``} {
+		_, _, err = decodeResultsStream(strings.NewReader(bad), func(dec *json.Decoder, root string) error {
+			var v json.RawMessage
+			return dec.Decode(&v)
+		})
+		if err == nil {
+			t.Fatalf("Expected an error decoding '%s'", bad)
+		}
+	}
+
+	count, _, err = decodeResultsStream(strings.NewReader(`{"results":null}`), nil)
+	if err != nil || count != 0 {
+		t.Fatalf("A null results array should be an empty page, count=%d err='%v'", count, err)
+	}
+}
+
+func TestStreamSystemsDeliversBeforeThePageEnds(t *testing.T) {
+	firstSeen := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"totalCount":2,"results":[{"_id":"first","hostname":"one"},`)
+		w.(http.Flusher).Flush()
+
+		// The rest of the page is only sent once the client has seen the first system
+		select {
+		case <-firstSeen:
+		case <-time.After(5 * time.Second):
+			return
+		}
+
+		fmt.Fprint(w, `{"_id":"second","hostname":"two"}]}`)
+	}))
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	var hostnames []string
+	err := jc.StreamSystems(context.Background(), PageOptions{}, func(system JCSystem) error {
+		if system.Id == "first" {
+			close(firstSeen)
+		}
+		hostnames = append(hostnames, system.Hostname)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("StreamSystems() failed, err='%s'", err)
+	}
+
+	if strings.Join(hostnames, ",") != "one,two" {
+		t.Fatalf("Unexpected systems streamed: %v", hostnames)
+	}
+}
+
+func TestStreamPagesAndStop(t *testing.T) {
+	var requests []string
+	ts := newListServer(t, 250, &requests)
+	defer ts.Close()
+
+	jc := NewJCAPI("fake-key", ts.URL)
+
+	count := 0
+	err := jc.StreamCommandResults(context.Background(), PageOptions{}, func(result JCCommandResult) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 250 || len(requests) != 3 {
+		t.Fatalf("Expected 250 results in 3 pages, got %d in %d, err='%v'", count, len(requests), err)
+	}
+
+	requests = nil
+	count = 0
+
+	err = jc.StreamSystemUsers(context.Background(), PageOptions{}, func(user JCUser) error {
+		count++
+		if count == 150 {
+			return ErrStopStream
+		}
+		return nil
+	})
+
+	if err != nil || count != 150 || len(requests) != 2 {
+		t.Fatalf("Expected to stop after 150 users in 2 pages, got %d in %d, err='%v'", count, len(requests), err)
+	}
+}
@@ -0,0 +1,158 @@
+package jcapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+//
+// The fields of a system that loosen or tighten how it can be logged into. JumpCloud
+// reads a missing value as false, so they are only sent when set explicitly.
+//
+var systemSecurityFields = map[string]bool{
+	"allowSshRootLogin":              true,
+	"allowSshPasswordAuthentication": true,
+	"allowPublicKeyAuthentication":   true,
+	"allowMultiFactorAuthentication": true,
+	"modifySSHDConfig":               true,
+	"sshRootEnabled":                 true,
+	"sshPassEnabled":                 true,
+}
+
+//
+// JCSystemChanges is a partial update of a system: it maps the JSON name of each field
+// to change onto its new value. Build one with the setters, as in
+//
+//	JCSystemChanges{}.SetDisplayName("web1").SetAllowSshRootLogin(false)
+//
+// or with NewSystemChanges(), and send it with UpdateSystemFields().
+//
+type JCSystemChanges map[string]interface{}
+
+// Fields returns the sorted names of the fields changed.
+func (changes JCSystemChanges) Fields() []string {
+	names := make([]string, 0, len(changes))
+
+	for name := range changes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (changes JCSystemChanges) SetDisplayName(displayName string) JCSystemChanges {
+	changes["displayName"] = displayName
+	return changes
+}
+
+func (changes JCSystemChanges) SetAllowSshRootLogin(allow bool) JCSystemChanges {
+	changes["allowSshRootLogin"] = allow
+	return changes
+}
+
+func (changes JCSystemChanges) SetAllowSshPasswordAuthentication(allow bool) JCSystemChanges {
+	changes["allowSshPasswordAuthentication"] = allow
+	return changes
+}
+
+func (changes JCSystemChanges) SetAllowPublicKeyAuthentication(allow bool) JCSystemChanges {
+	changes["allowPublicKeyAuthentication"] = allow
+	return changes
+}
+
+func (changes JCSystemChanges) SetAllowMultiFactorAuthentication(allow bool) JCSystemChanges {
+	changes["allowMultiFactorAuthentication"] = allow
+	return changes
+}
+
+func (changes JCSystemChanges) SetModifySSHDConfig(modify bool) JCSystemChanges {
+	changes["modifySSHDConfig"] = modify
+	return changes
+}
+
+// SetTags replaces the tags of the system with the given tag names or IDs, none clears them.
+func (changes JCSystemChanges) SetTags(tags ...string) JCSystemChanges {
+	changes["tags"] = append([]string{}, tags...)
+	return changes
+}
+
+//
+// NewSystemChanges returns a change set that sets the named fields (JSON names, such as
+// "displayName") to their value in system, whether or not they changed.
+//
+func NewSystemChanges(system JCSystem, fields ...string) (JCSystemChanges, error) {
+	changes := make(JCSystemChanges)
+	values := jsonFieldValues(system)
+
+	for _, name := range fields {
+		value, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("ERROR: '%s' is not a field of a system", name)
+		}
+
+		changes[name] = value
+	}
+
+	return changes, nil
+}
+
+// validate returns an error for the fields that can't be sent as they are
+func (changes JCSystemChanges) validate() error {
+	known := jsonFieldValues(JCSystem{})
+
+	for name, value := range changes {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("ERROR: '%s' is not a field of a system", name)
+		}
+
+		if _, ok := value.(bool); systemSecurityFields[name] && !ok {
+			return fmt.Errorf("ERROR: '%s' must be set explicitly to true or false, not %#v", name, value)
+		}
+	}
+
+	return nil
+}
+
+func (jc JCAPI) UpdateSystemFields(systemId string, changes JCSystemChanges) (JCSystem, JCError) {
+	return jc.UpdateSystemFieldsContext(context.Background(), systemId, changes)
+}
+
+//
+// UpdateSystemFieldsContext sends only the given changes to the system, leaving every
+// other field of the system as it is on JumpCloud, and returns the updated system.
+// Unlike UpdateSystem(), it can't turn off settings such as allowSshRootLogin or
+// allowMultiFactorAuthentication by accident: they are only sent when set, to a bool.
+//
+func (jc JCAPI) UpdateSystemFieldsContext(ctx context.Context, systemId string, changes JCSystemChanges) (system JCSystem, err JCError) {
+	if systemId == "" {
+		return system, fmt.Errorf("ERROR: Cannot update a system without its ID")
+	}
+
+	fields := make(map[string]interface{}, len(changes))
+
+	for name, value := range changes {
+		if name != "_id" {
+			fields[name] = value
+		}
+	}
+
+	err = JCSystemChanges(fields).validate()
+	if err != nil {
+		return system, err
+	}
+
+	// Nothing to change, don't bother JumpCloud with an empty update
+	if len(fields) == 0 {
+		return jc.GetSystemByIdContext(ctx, systemId, false)
+	}
+
+	err = jc.putFields(ctx, SYSTEMS_PATH+"/"+systemId, fields, &system)
+	if err != nil {
+		return system, fmt.Errorf("ERROR: Could not update fields %v of system ID '%s', err='%w'", changes.Fields(), systemId, err)
+	}
+
+	return
+}
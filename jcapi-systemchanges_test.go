@@ -0,0 +1,93 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSystemChangesSetters(t *testing.T) {
+	changes := JCSystemChanges{}.SetDisplayName("web1").SetAllowSshRootLogin(false).SetAllowMultiFactorAuthentication(true).SetTags()
+
+	if s := strings.Join(changes.Fields(), " "); s != "allowMultiFactorAuthentication allowSshRootLogin displayName tags" {
+		t.Fatalf("Unexpected fields changed: %s", s)
+	}
+
+	// Clearing the tags sends an empty list, not null
+	if data, _ := json.Marshal(changes); !strings.Contains(string(data), `"tags":[]`) {
+		t.Fatalf("Unexpected changes marshalled: %s", data)
+	}
+
+	changes, err := NewSystemChanges(JCSystem{DisplayName: "db1", AllowSshPasswordAuthentication: true}, "displayName", "allowSshPasswordAuthentication")
+	if err != nil || changes["displayName"] != "db1" || changes["allowSshPasswordAuthentication"] != true {
+		t.Fatalf("Unexpected changes %v, err='%v'", changes, err)
+	}
+
+	if _, err = NewSystemChanges(JCSystem{}, "DisplayName"); err == nil {
+		t.Fatalf("Expected an error for an unknown field")
+	}
+}
+
+func TestUpdateSystemFieldsSendsOnlyTheChanges(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","hostname":"web1","displayName":"web","allowSshRootLogin":true}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	system, err := jc.UpdateSystemFields("a", JCSystemChanges{"_id": "b"}.SetDisplayName("web"))
+	if err != nil {
+		t.Fatalf("UpdateSystemFields() failed, err='%s'", err)
+	}
+
+	if system.DisplayName != "web" || !system.AllowSshRootLogin {
+		t.Fatalf("Unexpected system returned: %+v", system)
+	}
+
+	request := rt.requests[0]
+	body, _ := ioutil.ReadAll(request.Body)
+
+	if request.Method != http.MethodPut || request.URL.Path != "/api/systems/a" || string(body) != `{"displayName":"web"}` {
+		t.Fatalf("Unexpected request %s %s '%s'", request.Method, request.URL, body)
+	}
+
+	// Security settings are never sent unless set to a bool, and only known fields are
+	for _, changes := range []JCSystemChanges{
+		{"allowSshRootLogin": nil},
+		{"allowMultiFactorAuthentication": "false"},
+		{"displayname": "web"},
+	} {
+		if _, err := jc.UpdateSystemFields("a", changes); err == nil {
+			t.Errorf("Expected an error sending %v", changes)
+		}
+	}
+
+	if len(rt.requests) != 1 {
+		t.Fatalf("Invalid changes were sent: %d requests", len(rt.requests))
+	}
+
+	// Nothing to change only reads the system back
+	_, err = jc.UpdateSystemFields("a", JCSystemChanges{})
+	if err != nil || rt.requests[1].Method != http.MethodGet {
+		t.Fatalf("Expected a GET for an empty change set, err='%v'", err)
+	}
+
+	if _, err = jc.UpdateSystemFields("", JCSystemChanges{}); err == nil {
+		t.Fatalf("Expected an error without a system ID")
+	}
+}
+
+func TestSystemNetworkInterfacesOmitted(t *testing.T) {
+	data, _ := json.Marshal(JCSystem{Id: "a"})
+	if strings.Contains(string(data), "networkInterfaces") {
+		t.Fatalf("Unexpected empty network interfaces marshalled: %s", data)
+	}
+
+	data, _ = json.Marshal(JCSystem{Id: "a", NetworkInterfaces: []JCNetworkInterface{{Name: "eth0"}}})
+	if !strings.Contains(string(data), `"networkInterfaces":[{"name":"eth0"`) {
+		t.Fatalf("Expected network interfaces marshalled: %s", data)
+	}
+}
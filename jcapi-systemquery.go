@@ -0,0 +1,340 @@
+package jcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ActiveState selects systems by whether their agent is connected
+type ActiveState int
+
+const (
+	AnyActiveState ActiveState = iota
+	OnlyActive
+	OnlyInactive
+)
+
+//
+// SystemQuery selects systems on typed criteria, with FindSystems() or Select(). The zero
+// SystemQuery selects every system, and each criterion set narrows the selection down.
+// The criteria the search API supports are sent to it, the others are checked locally.
+//
+type SystemQuery struct {
+	OS        string // the OS, such as "Ubuntu" or "Windows", compared exactly
+	OSPattern string // a regular expression the OS matches
+	Arch      string // such as "x86_64"
+	Version   string // the version of the OS, compared exactly
+
+	MinAgentVersion string // inclusive, versions are compared number by number ("1.10" > "1.9")
+	MaxAgentVersion string // inclusive
+
+	Active ActiveState
+
+	LastContactBefore time.Time // also selects the systems that never made contact
+	LastContactAfter  time.Time
+
+	RemoteIP         string // an address, or a CIDR block such as "10.0.0.0/8"
+	InterfaceAddress string // an address, or a CIDR block, of any of the network interfaces
+}
+
+//
+// Filter returns the criteria of the query the search API can check, for a SearchQuery.
+// The systems it returns still need to be checked with Predicate().
+//
+func (q SystemQuery) Filter() Filter {
+	var filters []Filter
+
+	if q.OS != "" {
+		filters = append(filters, Eq("os", q.OS))
+	}
+
+	if q.OSPattern != "" {
+		filters = append(filters, Regex("os", q.OSPattern))
+	}
+
+	if q.Arch != "" {
+		filters = append(filters, Eq("arch", q.Arch))
+	}
+
+	if q.Version != "" {
+		filters = append(filters, Eq("version", q.Version))
+	}
+
+	switch q.Active {
+	case OnlyActive:
+		filters = append(filters, Eq("active", true))
+	case OnlyInactive:
+		filters = append(filters, Eq("active", false))
+	}
+
+	// Systems that never made contact have no lastContact, and are "before" any time
+	if !q.LastContactBefore.IsZero() {
+		filters = append(filters, Or(Lt("lastContact", q.LastContactBefore.UTC().Format(time.RFC3339)), Missing("lastContact")))
+	}
+
+	if !q.LastContactAfter.IsZero() {
+		filters = append(filters, Gt("lastContact", q.LastContactAfter.UTC().Format(time.RFC3339)))
+	}
+
+	if ip := net.ParseIP(q.RemoteIP); ip != nil {
+		filters = append(filters, Eq("remoteIP", ip.String()))
+	}
+
+	if ip := net.ParseIP(q.InterfaceAddress); ip != nil {
+		filters = append(filters, Eq("networkInterfaces.address", ip.String()))
+	}
+
+	return And(filters...)
+}
+
+//
+// Predicate returns a function that reports whether a system matches every criterion of
+// the query. It fails if the query is invalid, such as a bad OSPattern or CIDR block.
+//
+func (q SystemQuery) Predicate() (func(JCSystem) bool, error) {
+	var osPattern *regexp.Regexp
+	var remoteNet, interfaceNet *net.IPNet
+	var err error
+
+	if q.OSPattern != "" {
+		osPattern, err = regexp.Compile(q.OSPattern)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Invalid OS pattern '%s', err='%w'", q.OSPattern, err)
+		}
+	}
+
+	if q.RemoteIP != "" {
+		remoteNet, err = parseIPNet(q.RemoteIP)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if q.InterfaceAddress != "" {
+		interfaceNet, err = parseIPNet(q.InterfaceAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, version := range []string{q.MinAgentVersion, q.MaxAgentVersion} {
+		if _, err := parseVersion(version); version != "" && err != nil {
+			return nil, fmt.Errorf("ERROR: Invalid agent version '%s', err='%w'", version, err)
+		}
+	}
+
+	return func(system JCSystem) bool {
+		switch {
+		case q.OS != "" && system.Os != q.OS:
+			return false
+		case osPattern != nil && !osPattern.MatchString(system.Os):
+			return false
+		case q.Arch != "" && system.Arch != q.Arch:
+			return false
+		case q.Version != "" && system.Version != q.Version:
+			return false
+		case q.Active == OnlyActive && !system.Active:
+			return false
+		case q.Active == OnlyInactive && system.Active:
+			return false
+		case q.MinAgentVersion != "" && compareVersions(system.AgentVersion, q.MinAgentVersion) < 0:
+			return false
+		case q.MaxAgentVersion != "" && compareVersions(system.AgentVersion, q.MaxAgentVersion) > 0:
+			return false
+		case remoteNet != nil && !containsIP(remoteNet, system.RemoteIP):
+			return false
+		case interfaceNet != nil && !hasInterfaceIn(system, interfaceNet):
+			return false
+		}
+
+		if !q.LastContactBefore.IsZero() || !q.LastContactAfter.IsZero() {
+			return q.matchLastContact(system)
+		}
+
+		return true
+	}, nil
+}
+
+func (q SystemQuery) matchLastContact(system JCSystem) bool {
+	if system.LastContact.IsZero() {
+		return q.LastContactAfter.IsZero()
+	}
+
+	lastContact := system.LastContact.Time()
+
+	if !q.LastContactBefore.IsZero() && !lastContact.Before(q.LastContactBefore) {
+		return false
+	}
+
+	if !q.LastContactAfter.IsZero() && !lastContact.After(q.LastContactAfter) {
+		return false
+	}
+
+	return true
+}
+
+// Select returns the systems that match the query, in the same order.
+func (q SystemQuery) Select(systems []JCSystem) ([]JCSystem, error) {
+	match, err := q.Predicate()
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []JCSystem
+
+	for _, system := range systems {
+		if match(system) {
+			selected = append(selected, system)
+		}
+	}
+
+	return selected, nil
+}
+
+func (jc JCAPI) FindSystems(q SystemQuery, withTags bool) ([]JCSystem, JCError) {
+	return jc.FindSystemsContext(context.Background(), q, withTags)
+}
+
+//
+// FindSystemsContext returns the systems that match q, sorted by hostname. The search
+// API narrows the systems down as far as it can, and the rest of q is checked locally.
+//
+func (jc JCAPI) FindSystemsContext(ctx context.Context, q SystemQuery, withTags bool) ([]JCSystem, JCError) {
+	match, err := q.Predicate()
+	if err != nil {
+		return nil, err
+	}
+
+	pager, err := jc.NewSearchPager(ctx, SEARCH_SYSTEMS_PATH, SearchQuery{Filter: q.Filter(), Sort: "hostname"})
+	if err != nil {
+		return nil, err
+	}
+
+	var systems []JCSystem
+
+	for pager.Next() {
+		var system JCSystem
+
+		err = pager.Decode(&system)
+		if err != nil {
+			return nil, err
+		}
+
+		if match(system) {
+			systems = append(systems, system)
+		}
+	}
+
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%w'", pager.Err())
+	}
+
+	if withTags {
+		tags, err := jc.GetAllTagsContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
+		}
+
+		for idx := range systems {
+			systems[idx].AddJCTagsToSystem(tags)
+		}
+	}
+
+	return systems, nil
+}
+
+// parseIPNet parses a CIDR block, or a single address as the block of only that address
+func parseIPNet(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: '%s' is neither an IP address nor a CIDR block, err='%w'", s, err)
+	}
+
+	return ipNet, nil
+}
+
+func containsIP(ipNet *net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ipNet.Contains(ip)
+}
+
+func hasInterfaceIn(system JCSystem, ipNet *net.IPNet) bool {
+	for _, networkInterface := range system.NetworkInterfaces {
+		if containsIP(ipNet, networkInterface.Address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseVersion returns the numbers of a dotted version such as "0.9.127", ignoring any suffix after '-'
+func parseVersion(version string) ([]int, error) {
+	version = strings.SplitN(strings.TrimPrefix(strings.TrimSpace(version), "v"), "-", 2)[0]
+
+	parts := strings.Split(version, ".")
+	numbers := make([]int, len(parts))
+
+	for idx, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+
+		numbers[idx] = n
+	}
+
+	return numbers, nil
+}
+
+//
+// compareVersions returns -1, 0 or 1 as a is older than, the same as or newer than b,
+// missing numbers counting as 0. A version that can't be parsed is older than any other.
+//
+func compareVersions(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+
+	for idx := 0; idx < len(va) || idx < len(vb); idx++ {
+		var na, nb int
+
+		if idx < len(va) {
+			na = va[idx]
+		}
+		if idx < len(vb) {
+			nb = vb[idx]
+		}
+
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+	}
+
+	return 0
+}
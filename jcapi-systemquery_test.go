@@ -0,0 +1,143 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+var querySystems = []JCSystem{
+	{Id: "a", Hostname: "web1", Os: "Ubuntu", Arch: "x86_64", Version: "14.04", AgentVersion: "0.9.127", Active: true,
+		LastContact: mustParseTimestamp("2016-05-04T12:00:00.000Z"), RemoteIP: "10.1.2.3",
+		NetworkInterfaces: []JCNetworkInterface{{Name: "eth0", Address: "192.168.1.10"}, {Name: "lo", Address: "127.0.0.1", Internal: true}}},
+	{Id: "b", Hostname: "mac1", Os: "Mac OS X", Arch: "x86_64", Version: "10.11", AgentVersion: "0.10.2", Active: false,
+		LastContact: mustParseTimestamp("2016-01-01T00:00:00Z"), RemoteIP: "203.0.113.7",
+		NetworkInterfaces: []JCNetworkInterface{{Name: "en0", Address: "fe80::1"}}},
+	{Id: "c", Hostname: "win1", Os: "Windows", Arch: "x86", AgentVersion: "bad", Active: false},
+}
+
+func selectedIds(t *testing.T, q SystemQuery) string {
+	selected, err := q.Select(querySystems)
+	if err != nil {
+		t.Fatalf("Select() failed for %+v, err='%s'", q, err)
+	}
+
+	var ids []string
+	for _, system := range selected {
+		ids = append(ids, system.Id)
+	}
+
+	return strings.Join(ids, " ")
+}
+
+func TestSystemQuerySelect(t *testing.T) {
+	tests := []struct {
+		query    SystemQuery
+		expected string
+	}{
+		{SystemQuery{}, "a b c"},
+		{SystemQuery{OS: "Ubuntu"}, "a"},
+		{SystemQuery{OSPattern: "(?i)mac|win"}, "b c"},
+		{SystemQuery{Arch: "x86_64", Version: "10.11"}, "b"},
+		{SystemQuery{Active: OnlyActive}, "a"},
+		{SystemQuery{Active: OnlyInactive}, "b c"},
+		{SystemQuery{MinAgentVersion: "0.9.200"}, "b"},
+		{SystemQuery{MaxAgentVersion: "0.9.127"}, "a c"},
+		{SystemQuery{MinAgentVersion: "0.9", MaxAgentVersion: "0.10"}, "a"},
+		{SystemQuery{LastContactBefore: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)}, "b c"},
+		{SystemQuery{LastContactAfter: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)}, "a"},
+		{SystemQuery{RemoteIP: "10.0.0.0/8"}, "a"},
+		{SystemQuery{RemoteIP: "203.0.113.7"}, "b"},
+		{SystemQuery{InterfaceAddress: "192.168.0.0/16"}, "a"},
+		{SystemQuery{InterfaceAddress: "fe80::/10"}, "b"},
+		{SystemQuery{InterfaceAddress: "127.0.0.1", Active: OnlyInactive}, ""},
+	}
+
+	for _, test := range tests {
+		if ids := selectedIds(t, test.query); ids != test.expected {
+			t.Errorf("Unexpected systems for %+v: got '%s', want '%s'", test.query, ids, test.expected)
+		}
+	}
+
+	for _, q := range []SystemQuery{{OSPattern: "("}, {RemoteIP: "10.0.0.0/33"}, {InterfaceAddress: "host"}, {MinAgentVersion: "x.y"}} {
+		if _, err := q.Predicate(); err == nil {
+			t.Errorf("Expected an error for %+v", q)
+		}
+	}
+}
+
+func TestSystemQueryFilterLastContact(t *testing.T) {
+	q := SystemQuery{
+		Active:            OnlyInactive,
+		LastContactBefore: time.Date(2016, 3, 1, 0, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+		LastContactAfter:  time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	body, err := SearchQuery{Filter: q.Filter()}.Body()
+	if err != nil {
+		t.Fatalf("Body() failed, err='%s'", err)
+	}
+
+	expected := `{"filter":[{"active":false},` +
+		`{"or":[{"lastContact":{"$lt":"2016-03-01T05:00:00Z"}},{"lastContact":null}]},` +
+		`{"lastContact":{"$gt":"2015-12-01T00:00:00Z"}}]}`
+	if string(body) != expected {
+		t.Fatalf("Unexpected body:\n got '%s'\nwant '%s'", body, expected)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"0.9.127", "0.10.2", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1", 0},
+		{"v2.1.0-beta", "2.1", 0},
+		{"bad", "0.1", -1},
+	}
+
+	for _, test := range tests {
+		if n := compareVersions(test.a, test.b); n != test.expected {
+			t.Errorf("compareVersions('%s', '%s') returned %d", test.a, test.b, n)
+		}
+	}
+}
+
+func TestFindSystemsSearchesThenFilters(t *testing.T) {
+	results, _ := json.Marshal(map[string]interface{}{"totalCount": len(querySystems), "results": querySystems})
+	rt := &recordingTransport{body: string(results)}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	q := SystemQuery{
+		Arch:             "x86_64",
+		Active:           OnlyInactive,
+		LastContactAfter: time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC),
+		RemoteIP:         "203.0.113.0/24",
+		InterfaceAddress: "fe80::1",
+		MinAgentVersion:  "0.10",
+	}
+
+	systems, err := jc.FindSystems(q, false)
+	if err != nil {
+		t.Fatalf("FindSystems() failed, err='%s'", err)
+	}
+
+	if len(systems) != 1 || systems[0].Id != "b" {
+		t.Fatalf("Unexpected systems: %v", systems)
+	}
+
+	body, _ := ioutil.ReadAll(rt.requests[0].Body)
+
+	expected := `{"filter":[{"arch":"x86_64"},{"active":false},{"lastContact":{"$gt":"2015-12-01T00:00:00Z"}},{"networkInterfaces.address":"fe80::1"}]}`
+	if string(body) != expected || rt.requests[0].URL.Path != "/api"+SEARCH_SYSTEMS_PATH {
+		t.Fatalf("Unexpected request %s '%s'", rt.requests[0].URL, body)
+	}
+}
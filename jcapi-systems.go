@@ -1,6 +1,7 @@
 package jcapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -14,32 +15,32 @@ type JCSystemResults struct {
 }
 
 type JCSystem struct {
-	Os                             string  `json:"os,omitempty"`
-	TemplateName                   string  `json:"templateName,omitempty"`
-	AllowSshRootLogin              bool    `json:"allowSshRootLogin"`
-	Id                             string  `json:"_id"`
-	LastContact                    string  `json:"lastContact,omitempty"`
-	RemoteIP                       string  `json:"remoteIP,omitempty"`
-	Active                         bool    `json:"active,omitempty"`
-	SshRootEnabled                 bool    `json:"sshRootEnabled"`
-	AmazonInstanceID               string  `json:"amazonInstanceID,omitempty"`
-	SshPassEnabled                 bool    `json:"sshPassEnabled,omitempty"`
-	Version                        string  `json:"version,omitempty"`
-	AgentVersion                   string  `json:"agentVersion,omitempty"`
-	AllowPublicKeyAuth             bool    `json:"allowPublicKeyAuthentication"`
-	Organization                   string  `json:"organization,omitempty"`
-	Created                        string  `json:"created,omitempty"`
-	Arch                           string  `json:"arch,omitempty"`
-	SystemTimezone                 float64 `json:"systemTimeZone,omitempty"`
-	AllowSshPasswordAuthentication bool    `json:"allowSshPasswordAuthentication"`
-	DisplayName                    string  `json:"displayName"`
-	ModifySSHDConfig               bool    `json:"modifySSHDConfig"`
-	AllowMultiFactorAuthentication bool    `json:"allowMultiFactorAuthentication"`
-	Hostname                       string  `json:"hostname,omitempty"`
+	Os                             string    `json:"os,omitempty"`
+	TemplateName                   string    `json:"templateName,omitempty"`
+	AllowSshRootLogin              bool      `json:"allowSshRootLogin"`
+	Id                             string    `json:"_id"`
+	LastContact                    Timestamp `json:"lastContact,omitzero"`
+	RemoteIP                       string    `json:"remoteIP,omitempty"`
+	Active                         bool      `json:"active,omitempty"`
+	SshRootEnabled                 bool      `json:"sshRootEnabled"`
+	AmazonInstanceID               string    `json:"amazonInstanceID,omitempty"`
+	SshPassEnabled                 bool      `json:"sshPassEnabled,omitempty"`
+	Version                        string    `json:"version,omitempty"`
+	AgentVersion                   string    `json:"agentVersion,omitempty"`
+	AllowPublicKeyAuth             bool      `json:"allowPublicKeyAuthentication"`
+	Organization                   string    `json:"organization,omitempty"`
+	Created                        Timestamp `json:"created,omitzero"`
+	Arch                           string    `json:"arch,omitempty"`
+	SystemTimezone                 float64   `json:"systemTimeZone,omitempty"`
+	AllowSshPasswordAuthentication bool      `json:"allowSshPasswordAuthentication"`
+	DisplayName                    string    `json:"displayName"`
+	ModifySSHDConfig               bool      `json:"modifySSHDConfig"`
+	AllowMultiFactorAuthentication bool      `json:"allowMultiFactorAuthentication"`
+	Hostname                       string    `json:"hostname,omitempty"`
 
 	ConnectionHistoryList []string             `json:"connectionHistory,omitempty"`
 	SshdParams            []JCSSHDParam        `json:"sshdParams,omitempty"`
-	NetworkInterfaces     []JCNetworkInterface `json:"networkInterfaces, omitempty"`
+	NetworkInterfaces     []JCNetworkInterface `json:"networkInterfaces,omitempty"`
 
 	// Derived by JCAPI
 	TagList []string `json:"tags,omitempty"`
@@ -111,27 +112,19 @@ func GetInterfaceArrayFromJCSystems(systems []JCSystem) (interfaceArray []interf
 
 // Executes a search by hostname via the JumpCloud API
 func (jc JCAPI) GetSystemByHostName(hostname string, withTags bool) ([]JCSystem, JCError) {
-	var returnVal []JCSystem
-
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(Insert), "/search"+SYSTEMS_PATH, jc.hostnameFilter(hostname))
-
-	if err != nil {
-		return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%s'", err)
-	}
-
-	systemResults := JCSystemResults{}
+	return jc.GetSystemByHostNameContext(context.Background(), hostname, withTags)
+}
 
-	err = json.Unmarshal(buffer, &systemResults)
+func (jc JCAPI) GetSystemByHostNameContext(ctx context.Context, hostname string, withTags bool) ([]JCSystem, JCError) {
+	returnVal, err := jc.SearchSystemsContext(ctx, SearchQuery{Filter: Eq("hostname", hostname)})
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
+		return nil, err
 	}
 
-	returnVal = systemResults.Results
-
 	if withTags {
-		tags, err := jc.GetAllTags()
+		tags, err := jc.GetAllTagsContext(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not get tags, err='%s'", err)
+			return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
 		}
 
 		for idx, _ := range returnVal {
@@ -143,25 +136,26 @@ func (jc JCAPI) GetSystemByHostName(hostname string, withTags bool) ([]JCSystem,
 }
 
 func (jc JCAPI) GetSystemById(systemId string, withTags bool) (system JCSystem, err JCError) {
+	return jc.GetSystemByIdContext(context.Background(), systemId, withTags)
+}
+
+func (jc JCAPI) GetSystemByIdContext(ctx context.Context, systemId string, withTags bool) (system JCSystem, err JCError) {
 	url := fmt.Sprintf("%s/%s", SYSTEMS_PATH, systemId)
 
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(Read), url, nil)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), url, nil)
 	if err != nil {
-		return system, fmt.Errorf("ERROR: Could not get system by ID '%s', err='%s'", systemId, err.Error())
+		return system, fmt.Errorf("ERROR: Could not get system by ID '%s', err='%w'", systemId, err)
 	}
 
-	err = json.Unmarshal(buffer, &system)
+	err = decodeJSON(buffer, &system)
 	if err != nil {
-		return system, fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
+		return system, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 	}
 
 	if withTags {
-		// I should be able to use err below as the err return value, but there's
-		// a compiler bug here in that it thinks a := of err is shadowed here,
-		// even though tags should be the only variable declared with the :=
-		tags, err2 := jc.GetAllTags()
-		if err != nil {
-			err = fmt.Errorf("ERROR: Could not get tags, err='%s'", err2)
+		tags, err2 := jc.GetAllTagsContext(ctx)
+		if err2 != nil {
+			err = fmt.Errorf("ERROR: Could not get tags, err='%w'", err2)
 			return
 		}
 
@@ -172,31 +166,22 @@ func (jc JCAPI) GetSystemById(systemId string, withTags bool) (system JCSystem,
 }
 
 func (jc JCAPI) GetSystems(withTags bool) (systems []JCSystem, err JCError) {
-	for skip := 0; skip == 0 || len(systems) == searchLimit; skip += searchSkipInterval {
-		url := fmt.Sprintf("%s?sort=hostname&skip=%d&limit=%d", SYSTEMS_PATH, skip, searchLimit)
-
-		buffer, err2 := jc.DoBytes(MapJCOpToHTTP(Read), url, nil)
-		if err2 != nil {
-			return nil, fmt.Errorf("ERROR: Get to JumpCloud failed, err='%s'", err2)
-		}
-
-		systemResults := JCSystemResults{}
-
-		err = json.Unmarshal(buffer, &systemResults)
-		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
-		}
-
-		for _, system := range systemResults.Results {
-			systems = append(systems, system)
-		}
+	return jc.GetSystemsContext(context.Background(), withTags)
+}
 
+func (jc JCAPI) GetSystemsContext(ctx context.Context, withTags bool) (systems []JCSystem, err JCError) {
+	err = jc.StreamSystems(ctx, PageOptions{}, func(system JCSystem) error {
+		systems = append(systems, system)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Get to JumpCloud failed, err='%w'", err)
 	}
 
 	if withTags {
-		tags, err := jc.GetAllTags()
+		tags, err := jc.GetAllTagsContext(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not get tags, err='%s'", err)
+			return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
 		}
 
 		for idx, _ := range systems {
@@ -208,24 +193,30 @@ func (jc JCAPI) GetSystems(withTags bool) (systems []JCSystem, err JCError) {
 }
 
 //
-// Update a system
+// Update a system, sending all of its fields: the settings left false in system, such
+// as allowSshRootLogin or allowMultiFactorAuthentication, are turned off. To change
+// only some fields, use UpdateSystemFields().
 //
 func (jc JCAPI) UpdateSystem(system JCSystem) (systemId string, err JCError) {
+	return jc.UpdateSystemContext(context.Background(), system)
+}
+
+func (jc JCAPI) UpdateSystemContext(ctx context.Context, system JCSystem) (systemId string, err JCError) {
 	data, err := json.Marshal(system)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not marshal JCSystem object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not marshal JCSystem object, err='%w'", err)
 	}
 
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(Update), SYSTEMS_PATH+"/"+system.Id, data)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Update), SYSTEMS_PATH+"/"+system.Id, data)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not update JCSystem object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not update JCSystem object, err='%w'", err)
 	}
 
 	var returnSystem JCSystem
 
-	err = json.Unmarshal(buffer, &returnSystem)
+	err = decodeJSON(buffer, &returnSystem)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not unmarshal result buffer '%s', err='%s'", buffer, err.Error())
+		return "", fmt.Errorf("ERROR: Could not unmarshal result buffer, err='%w'", err)
 	}
 
 	if returnSystem.Id != system.Id {
@@ -245,9 +236,13 @@ func (jc JCAPI) UpdateSystem(system JCSystem) (systemId string, err JCError) {
 //    You will lose control of the system after the call returns.
 //
 func (jc JCAPI) DeleteSystem(system JCSystem) JCError {
-	_, err := jc.Delete(fmt.Sprintf("%s/%s", SYSTEMS_PATH, system.Id))
+	return jc.DeleteSystemContext(context.Background(), system)
+}
+
+func (jc JCAPI) DeleteSystemContext(ctx context.Context, system JCSystem) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("%s/%s", SYSTEMS_PATH, system.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete system '%s': err='%s'", system.Hostname, err)
+		return fmt.Errorf("ERROR: Could not delete system '%s': err='%w'", system.Hostname, err)
 	}
 
 	return nil
@@ -256,30 +251,34 @@ func (jc JCAPI) DeleteSystem(system JCSystem) JCError {
 // GetSystemUserBindingsById returns all the user bindings for the given system Id:
 // this includes the direct system-user bindings as well as the bindings made via tags
 func (jc JCAPI) GetSystemUserBindingsById(systemId string) (systemUserBindings []SystemUserBinding, err JCError) {
+	return jc.GetSystemUserBindingsByIdContext(context.Background(), systemId)
+}
+
+func (jc JCAPI) GetSystemUserBindingsByIdContext(ctx context.Context, systemId string) (systemUserBindings []SystemUserBinding, err JCError) {
 	url := fmt.Sprintf("%s/%s/systemusers", SYSTEMS_PATH, systemId)
 
-	buffer, err := jc.DoBytes(MapJCOpToHTTP(Read), url, nil)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), url, nil)
 	if err != nil {
-		return systemUserBindings, fmt.Errorf("ERROR: Could not get system user bindings for system ID '%s', err='%s'", systemId, err.Error())
+		return systemUserBindings, fmt.Errorf("ERROR: Could not get system user bindings for system ID '%s', err='%w'", systemId, err)
 	}
 
 	// The response from the /systems/<system_id>/users endpoint is a map of system-user bindings
 	// keyed on the user ID, so we can't unmarshall it in one operation:
 	// first parse the response into a map of generic json objects:
-	var userBindingMap map[string]*json.RawMessage
-	err = json.Unmarshal(buffer, &userBindingMap)
+	var userBindingMap map[string]json.RawMessage
+	err = decodeJSON(buffer, &userBindingMap)
 
 	if err != nil {
-		return systemUserBindings, fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", buffer, err.Error())
+		return systemUserBindings, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 	}
 
 	// iterate over the map of user bindings:
 	for userId, _ := range userBindingMap {
 		var binding SystemUserBinding
 		// unmarshall the current raw json into a system user binding:
-		err = json.Unmarshal(*userBindingMap[userId], &binding)
+		err = decodeJSONAt(userBindingMap[userId], "$"+formatPathKey(userId), &binding)
 		if err != nil {
-			return systemUserBindings, fmt.Errorf("ERROR: Could not unmarshal buffer '%s', err='%s'", *userBindingMap[userId], err.Error())
+			return systemUserBindings, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 		}
 		// set the user id (obtained from the current key)
 		// since it isn't populated in the json response:
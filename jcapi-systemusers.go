@@ -1,9 +1,14 @@
 package jcapi
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,7 +17,30 @@ type JCUserAttribute struct {
 	Value string `json:"value"`
 }
 
-// If you add a field here make sure to add corresponding logic to getJCUserFieldsFromInterface
+const (
+	// Number of concurrent requests GetSystemUsers() makes to fetch user details, unless
+	// changed with WithDetailConcurrency()
+	DefaultDetailConcurrency int = 8
+)
+
+//
+// The fields of a user synced from a directory the list endpoint may leave out, that
+// GetSystemUserById() returns. The other users don't have them at all.
+//
+var userDetailFields = []string{"external_dn", "external_source_type"}
+
+// WithDetailConcurrency sets how many users GetSystemUsers() fetches the details of at once.
+func WithDetailConcurrency(concurrency int) Option {
+	return func(cfg *clientConfig) {
+		cfg.detailConcurrency = concurrency
+	}
+}
+
+//
+// JCUser is (un)marshalled by MarshalJSON()/UnmarshalJSON() below. Fields the API sends
+// that have no counterpart here are kept in Extras, and written back out on marshal,
+// though not sent by AddUpdateUser(): many of them are computed by JumpCloud.
+//
 type JCUser struct {
 	Id                          string    `json:"_id,omitempty"`
 	UserName                    string    `json:"username,omitempty"`
@@ -20,12 +48,12 @@ type JCUser struct {
 	LastName                    string    `json:"lastname,omitempty"`
 	Email                       string    `json:"email"`
 	Password                    string    `json:"password,omitempty"`
-	PasswordDate                string    `json:"password_date,omitempty"`
+	PasswordDate                Timestamp `json:"password_date,omitzero"`
 	Activated                   bool      `json:"activated"`
 	ActivationKey               string    `json:"activation_key"`
 	ExpiredWarned               bool      `json:"expired_warned"`
 	PasswordExpired             bool      `json:"password_expired"`
-	PasswordExpirationDate      time.Time `json:"password_expiration_date,omitempty"`
+	PasswordExpirationDate      Timestamp `json:"password_expiration_date,omitzero"`
 	PendingProvisioning         bool      `json:"pendingProvisioning,omitempty"`
 	Sudo                        bool      `json:"sudo"`
 	Uid                         string    `json:"unix_uid"`
@@ -33,11 +61,15 @@ type JCUser struct {
 	EnableManagedUid            bool      `json:"enable_managed_uid"`
 	EnableUserPortalMultifactor bool      `json:"enable_user_portal_multifactor"`
 	TotpEnabled                 bool      `json:"totp_enabled"`
+	AccountLocked               bool      `json:"account_locked,omitempty"`
+	Suspended                   bool      `json:"suspended,omitempty"`
 
 	Attributes []JCUserAttribute `json:"attributes,omitempty"`
 
 	TagIds []string `json:"tags,omitempty"` // the list of tag IDs that this user should be put in
 
+	SSHKeys []JCUserSSHKey `json:"ssh_keys,omitempty"` // managed with AddUserSSHKey() and friends
+
 	//
 	// For identification as an external user directory source
 	//
@@ -45,7 +77,9 @@ type JCUser struct {
 	ExternalDN         string `json:"external_dn,omitempty"`
 	ExternalSourceType string `json:"external_source_type,omitempty"`
 
-	Tags []JCTag // the list of actual tags the user is in
+	Tags []JCTag `json:"-"` // the list of actual tags the user is in
+
+	Extras map[string]json.RawMessage `json:"-"` // fields returned by the API that JCUser doesn't know about
 }
 
 //
@@ -101,135 +135,212 @@ func setTagIds(user *JCUser) {
 	}
 }
 
-func getJCUserFieldsFromInterface(fields map[string]interface{}, user *JCUser) error {
-	user.Email = fields["email"].(string)
+//
+// jsonString decodes a JSON string, number or boolean into its string form, so that
+// fields the API sometimes returns as numbers (unix_uid and unix_guid, defect #96322248)
+// can still be read. A null leaves it empty.
+//
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(data []byte) error {
+	var value interface{}
 
-	if _, exists := fields["firstname"]; exists {
-		user.FirstName = fields["firstname"].(string)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err != nil {
+		return err
 	}
-	if _, exists := fields["lastname"]; exists {
-		user.LastName = fields["lastname"].(string)
+
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = jsonString(v)
+	case json.Number:
+		*s = jsonString(v.String())
+	case bool:
+		*s = jsonString(strconv.FormatBool(v))
+	default:
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeOf("")}
 	}
 
-	user.UserName = fields["username"].(string)
-	user.Id = fields["_id"].(string)
+	return nil
+}
 
-	if _, exists := fields["externally_managed"]; exists {
-		user.ExternallyManaged = fields["externally_managed"].(bool)
-	} else {
-		user.ExternallyManaged = false
+func (attribute *JCUserAttribute) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Name  json.RawMessage `json:"name"`
+		Value json.RawMessage `json:"value"`
 	}
 
-	user.Sudo = fields["sudo"].(bool)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var name, value jsonString
 
-	if _, exists := fields["external_dn"]; exists {
-		user.ExternalDN = fields["external_dn"].(string)
+	if err := unmarshalField("name", fields.Name, &name); err != nil {
+		return err
 	}
 
-	if _, exists := fields["external_source_type"]; exists {
-		user.ExternalSourceType = fields["external_source_type"].(string)
+	if err := unmarshalField("value", fields.Value, &value); err != nil {
+		return err
+	}
+
+	attribute.Name = string(name)
+	attribute.Value = string(value)
+
+	return nil
+}
+
+// jcUserFields has the same fields as JCUser, without its methods
+type jcUserFields JCUser
+
+//
+// jcUserJSON overrides the JCUser fields whose JSON form doesn't map directly onto
+// their Go type. The outer fields shadow the embedded ones with the same JSON name.
+//
+type jcUserJSON struct {
+	*jcUserFields
+
+	Uid jsonString `json:"unix_uid"`
+	Gid jsonString `json:"unix_guid"`
+}
+
+// jcUserKnownFields lists the JSON names of the fields decoded into JCUser itself
+var jcUserKnownFields = jsonFieldNames(reflect.TypeOf(JCUser{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
 	}
 
-	// Currently returned as float64, not string (though they are posted as string),
-	// defect #96322248...
-	if floatVal, ok := fields["unix_uid"].(float64); ok {
-		user.Uid = strconv.FormatInt(int64(floatVal), 10)
+	return names
+}
+
+func (user JCUser) MarshalJSON() ([]byte, error) {
+	fields := jcUserFields(user)
+
+	data, err := json.Marshal(jcUserJSON{
+		jcUserFields: &fields,
+		Uid:          jsonString(user.Uid),
+		Gid:          jsonString(user.Gid),
+	})
+	if err != nil || len(user.Extras) == 0 {
+		return data, err
 	}
-	if floatVal, ok := fields["unix_guid"].(float64); ok {
-		user.Gid = strconv.FormatInt(int64(floatVal), 10)
+
+	// Write the fields we don't know about back out, without letting them override ours
+	var merged map[string]json.RawMessage
+
+	err = json.Unmarshal(data, &merged)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, exists := fields["enable_managed_uid"]; exists {
-		user.EnableManagedUid = fields["enable_managed_uid"].(bool)
+	for name, value := range user.Extras {
+		if _, exists := merged[name]; !exists {
+			merged[name] = value
+		}
 	}
 
-	if _, exists := fields["password_expired"]; exists {
-		user.PasswordExpired = fields["password_expired"].(bool)
+	return json.Marshal(merged)
+}
+
+func (user *JCUser) UnmarshalJSON(data []byte) error {
+	fields := jcUserFields{}
+
+	// The fields with a custom decoding are decoded one by one below, so that errors name them
+	decoded := struct {
+		*jcUserFields
+
+		Uid                    json.RawMessage   `json:"unix_uid"`
+		Gid                    json.RawMessage   `json:"unix_guid"`
+		PasswordExpirationDate json.RawMessage   `json:"password_expiration_date"`
+		Attributes             []json.RawMessage `json:"attributes"`
+	}{jcUserFields: &fields}
+
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
 	}
 
-	if _, exists := fields["activated"]; exists {
-		user.Activated = fields["activated"].(bool)
+	var uid, gid jsonString
+	var passwordExpirationDate Timestamp
+
+	if err = unmarshalField("unix_uid", decoded.Uid, &uid); err != nil {
+		return err
 	}
 
-	if _, exists := fields["pendingProvisioning"]; exists {
-		user.PendingProvisioning = fields["pendingProvisioning"].(bool)
+	if err = unmarshalField("unix_guid", decoded.Gid, &gid); err != nil {
+		return err
 	}
 
-	if _, exists := fields["password_date"]; exists {
-		user.PasswordDate = fields["password_date"].(string)
+	if err = unmarshalField("password_expiration_date", decoded.PasswordExpirationDate, &passwordExpirationDate); err != nil {
+		return err
 	}
 
-	if _, exists := fields["password_expiration_date"]; exists {
-		var err error
-		user.PasswordExpirationDate, err = time.Parse(time.RFC3339, fields["password_expiration_date"].(string))
+	fields.Uid = string(uid)
+	fields.Gid = string(gid)
+	fields.PasswordExpirationDate = passwordExpirationDate
+
+	for idx, attribute := range decoded.Attributes {
+		fields.Attributes = append(fields.Attributes, JCUserAttribute{})
+
+		err = unmarshalField(fmt.Sprintf("attributes.%d", idx), attribute, &fields.Attributes[idx])
 		if err != nil {
 			return err
 		}
 	}
-  
-	if _, exists := fields["enable_user_portal_multifactor"]; exists {
-		user.EnableUserPortalMultifactor = fields["enable_user_portal_multifactor"].(bool)
-	}
-  
-	if _, exists := fields["totp_enabled"]; exists {
-		user.TotpEnabled = fields["totp_enabled"].(bool)
+
+	var all map[string]json.RawMessage
+
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		return err
 	}
 
-	if attrs, exists := fields["attributes"]; exists {
-		attributes := attrs.([]interface{})
-		for _, attributeMapInt := range attributes {
-			attributeMap := attributeMapInt.(map[string]interface{})
-			attrName, nameExists := attributeMap["name"]
-			if nameExists {
-				attrValue, valueExists := attributeMap["value"]
-				if valueExists {
-					user.Attributes = append(user.Attributes, JCUserAttribute{Name: attrName.(string), Value: attrValue.(string)})
-				}
+	for name, value := range all {
+		if !jcUserKnownFields[name] {
+			if fields.Extras == nil {
+				fields.Extras = make(map[string]json.RawMessage)
 			}
+			fields.Extras[name] = value
 		}
 	}
 
+	*user = JCUser(fields)
+
 	return nil
 }
 
-func getJCUsersFromInterface(userInt interface{}) ([]JCUser, error) {
-
-	var returnVal []JCUser
-
-	recMap := userInt.(map[string]interface{})
-
-	results := recMap["results"].([]interface{})
-
-	returnVal = make([]JCUser, len(results))
-
-	for idx, result := range results {
-		err := getJCUserFieldsFromInterface(result.(map[string]interface{}), &returnVal[idx])
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return returnVal, nil
+// JCUserResults is the envelope of a list or search of system users
+type JCUserResults struct {
+	TotalCount int      `json:"totalCount"`
+	Results    []JCUser `json:"results"`
 }
 
 // Executes a search by email via the JumpCloud API
 func (jc JCAPI) GetSystemUserByEmail(email string, withTags bool) ([]JCUser, JCError) {
-	var returnVal []JCUser
-
-	jcUserRec, err := jc.Post("/search/systemusers", jc.emailFilter(email))
-	if err != nil {
-		return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%s'", err)
-	}
+	return jc.GetSystemUserByEmailContext(context.Background(), email, withTags)
+}
 
-	returnVal, err = getJCUsersFromInterface(jcUserRec)
+func (jc JCAPI) GetSystemUserByEmailContext(ctx context.Context, email string, withTags bool) ([]JCUser, JCError) {
+	returnVal, err := jc.SearchSystemUsersContext(ctx, SearchQuery{Filter: Eq("email", email)})
 	if err != nil {
 		return nil, err
 	}
 
 	if withTags {
-		tags, err := jc.GetAllTags()
+		tags, err := jc.GetAllTagsContext(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not get tags, err='%s'", err)
+			return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
 		}
 
 		for idx, _ := range returnVal {
@@ -241,27 +352,28 @@ func (jc JCAPI) GetSystemUserByEmail(email string, withTags bool) ([]JCUser, JCE
 }
 
 func (jc JCAPI) GetSystemUserById(userId string, withTags bool) (user JCUser, err JCError) {
+	return jc.GetSystemUserByIdContext(context.Background(), userId, withTags)
+}
+
+func (jc JCAPI) GetSystemUserByIdContext(ctx context.Context, userId string, withTags bool) (user JCUser, err JCError) {
 	url := fmt.Sprintf("/systemusers/%s", userId)
 
-	retVal, err := jc.Get(url)
+	buffer, err := jc.GetContext(ctx, url)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not get system user by ID '%s', err='%s'", userId, err)
+		err = fmt.Errorf("ERROR: Could not get system user by ID '%s', err='%w'", userId, err)
 		return user, err
 	}
 
-	if retVal != nil {
-		err := getJCUserFieldsFromInterface(retVal.(map[string]interface{}), &user)
+	if len(buffer) > 0 {
+		err = decodeJSON(buffer, &user)
 		if err != nil {
-			return user, err
+			return user, fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 		}
 
 		if withTags {
-			// I should be able to use err below as the err return value, but there's
-			// a compiler bug here in that it thinks a := of err is shadowed here,
-			// even though tags should be the only variable declared with the :=
-			tags, err2 := jc.GetAllTags()
-			if err != nil {
-				err = fmt.Errorf("ERROR: Could not get tags, err='%s'", err2)
+			tags, err2 := jc.GetAllTagsContext(ctx)
+			if err2 != nil {
+				err = fmt.Errorf("ERROR: Could not get tags, err='%w'", err2)
 				return user, err
 			}
 
@@ -274,54 +386,63 @@ func (jc JCAPI) GetSystemUserById(userId string, withTags bool) (user JCUser, er
 }
 
 func (jc JCAPI) GetSystemUsers(withTags bool) (userList []JCUser, err JCError) {
-	var returnVal []JCUser
+	return jc.GetSystemUsersContext(context.Background(), withTags)
+}
+
+//
+// GetSystemUsersContext returns every system user, sorted by username. The list endpoint
+// leaves out some of the details of the users synced from a directory (such as the
+// external DN), so the users whose list record is missing them are fetched one by one,
+// by up to WithDetailConcurrency() requests at a time; the users the list reports as
+// not externally managed are never fetched. If any of those fail, the error for the
+// first such user in the list is returned.
+//
+func (jc JCAPI) GetSystemUsersContext(ctx context.Context, withTags bool) (userList []JCUser, err JCError) {
+	pager := jc.NewPager(ctx, "/systemusers", PageOptions{Sort: "username"})
+
+	var pending []int // the index in userList of each user that needs its details fetched
 
-	for skip := 0; skip == 0 || len(returnVal) == searchLimit; skip += searchSkipInterval {
-		url := fmt.Sprintf("/systemusers?sort=username&skip=%d&limit=%d", skip, searchLimit)
+	for pager.Next() {
+		var user JCUser
 
-		jcUserRec, err2 := jc.Get(url)
+		err = pager.Decode(&user)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Post to JumpCloud failed, err='%s'", err2)
+			return nil, err
 		}
 
-		if jcUserRec == nil {
-			return nil, fmt.Errorf("ERROR: No users found")
+		if user.Id == "" {
+			continue
 		}
 
-		// We really only care about the ID for the following call...
-		returnVal, err = getJCUsersFromInterface(jcUserRec)
-		if err != nil {
-			return
+		if !hasUserDetails(pager.Value()) {
+			pending = append(pending, len(userList))
 		}
 
-		for i, _ := range returnVal {
-			if returnVal[i].Id != "" {
+		userList = append(userList, user)
+	}
 
-				//
-				// Get the rest of the user record, which includes details like
-				// the externalDN...
-				//
-				// We'll get all the tags one time later, so don't get the tags on this call...
-				//
-				// See above about the compiler error that requires me to use err2 instead of err below...
-				//
-				detailedUser, err2 := jc.GetSystemUserById(returnVal[i].Id, false)
-				if err != nil {
-					err = fmt.Errorf("ERROR: Could not get details for user ID '%s', err='%s'", returnVal[i].Id, err2)
-					return
-				}
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Get to JumpCloud failed, err='%w'", pager.Err())
+	}
 
-				if detailedUser.Id != "" {
-					userList = append(userList, detailedUser)
-				}
-			}
+	err = jc.getUserDetails(ctx, userList, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the users that disappeared between the list and the detail calls
+	detailedUsers := userList[:0]
+	for _, user := range userList {
+		if user.Id != "" {
+			detailedUsers = append(detailedUsers, user)
 		}
 	}
+	userList = detailedUsers
 
 	if withTags {
-		tags, err := jc.GetAllTags()
+		tags, err := jc.GetAllTagsContext(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not get tags, err='%s'", err)
+			return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
 		}
 
 		for idx, _ := range userList {
@@ -333,10 +454,103 @@ func (jc JCAPI) GetSystemUsers(withTags bool) (userList []JCUser, err JCError) {
 	return
 }
 
+// hasUserDetails reports whether a user record from the list endpoint is as complete as
+// the one GetSystemUserById() would return
+func hasUserDetails(record json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+
+	if json.Unmarshal(record, &fields) != nil {
+		return false
+	}
+
+	// A user that isn't synced from a directory has no details to fetch
+	var externallyManaged bool
+	if value, ok := fields["externally_managed"]; ok && json.Unmarshal(value, &externallyManaged) == nil && !externallyManaged {
+		return true
+	}
+
+	for _, name := range userDetailFields {
+		if _, ok := fields[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// getUserDetails replaces each of the users at the pending indexes with the full record
+// from GetSystemUserById(), using up to jc.detailConcurrency requests at a time.
+//
+// pending is sent to the workers in order, and nothing new is sent once a request has
+// failed, so every user before the first failure is always fetched: the error returned
+// is always the one of the first user that failed, whatever the timing of the requests.
+//
+func (jc JCAPI) getUserDetails(ctx context.Context, users []JCUser, pending []int) error {
+	workers := jc.detailConcurrency
+	if workers <= 0 {
+		workers = DefaultDetailConcurrency
+	}
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	errs := make([]error, len(users))
+	indexes := make(chan int)
+	failed := make(chan struct{})
+
+	var failOnce sync.Once
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range indexes {
+				// We'll get all the tags one time later, so don't get the tags on this call...
+				detailedUser, err := jc.GetSystemUserByIdContext(ctx, users[idx].Id, false)
+				if err != nil {
+					errs[idx] = fmt.Errorf("ERROR: Could not get details for user ID '%s', err='%w'", users[idx].Id, err)
+					failOnce.Do(func() { close(failed) })
+					continue
+				}
+
+				users[idx] = detailedUser
+			}
+		}()
+	}
+
+dispatch:
+	for _, idx := range pending {
+		select {
+		case indexes <- idx:
+		case <-failed:
+			break dispatch
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 //
 // Resend user email
 //
 func (jc JCAPI) SendUserActivationEmail(userList []JCUser) (err JCError) {
+	return jc.SendUserActivationEmailContext(context.Background(), userList)
+}
+
+func (jc JCAPI) SendUserActivationEmailContext(ctx context.Context, userList []JCUser) (err JCError) {
 	for _, user := range userList {
 		if user.Id == "" {
 			return fmt.Errorf("ERROR: Cannot resend user activation email without a systemuser Id on user %v", user)
@@ -350,30 +564,47 @@ func (jc JCAPI) SendUserActivationEmail(userList []JCUser) (err JCError) {
 
 	data, err := json.Marshal(emailRequest)
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not marshal JCUserEmailRequest object, err='%s'", err)
+		return fmt.Errorf("ERROR: Could not marshal JCUserEmailRequest object, err='%w'", err)
 	}
 
 	url := "/systemusers/reactivate"
 
-	_, err = jc.Do(MapJCOpToHTTP(Insert), url, data)
+	_, err = jc.DoBytesContext(ctx, MapJCOpToHTTP(Insert), url, data)
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not post resend email request object, err='%s'", err)
+		return fmt.Errorf("ERROR: Could not post resend email request object, err='%w'", err)
 	}
 
 	return
 }
 
 //
-// Add or Update a new user to JumpCloud
+// Add or Update a new user to JumpCloud. When the JCAPI object has a password policy,
+// a password that breaks it is not sent, and a *PasswordPolicyError is returned instead.
+// The Extras of the user are not sent, as they are mostly read-only fields read from
+// JumpCloud; send the ones to change with UpdateUserFields(). Nor are its SSHKeys, which
+// are managed with AddUserSSHKey() and friends, so that an update made from a stale copy
+// of the user can't overwrite them.
 //
 func (jc JCAPI) AddUpdateUser(op JCOp, user JCUser) (userId string, err JCError) {
+	return jc.AddUpdateUserContext(context.Background(), op, user)
+}
+
+func (jc JCAPI) AddUpdateUserContext(ctx context.Context, op JCOp, user JCUser) (userId string, err JCError) {
+	err = jc.passwordPolicy.Validate(user)
+	if err != nil {
+		return "", err
+	}
+
 	if user.Password != "" {
-		user.PasswordDate = getTimeString()
+		user.PasswordDate = NewTimestamp(time.Now().Truncate(time.Second))
 	}
 
+	user.Extras = nil
+	user.SSHKeys = nil
+
 	data, err := json.Marshal(user)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not marshal JCUser object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not marshal JCUser object, err='%w'", err)
 	}
 
 	url := "/systemusers"
@@ -381,15 +612,16 @@ func (jc JCAPI) AddUpdateUser(op JCOp, user JCUser) (userId string, err JCError)
 		url += "/" + user.Id
 	}
 
-	jcUserRec, err := jc.Do(MapJCOpToHTTP(op), url, data)
+	buffer, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(op), url, data)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not post new JCUser object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not post new JCUser object, err='%w'", err)
 	}
 
 	var returnUser JCUser
-	err = getJCUserFieldsFromInterface(jcUserRec.(map[string]interface{}), &returnUser)
+
+	err = decodeJSON(buffer, &returnUser)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("ERROR: Could not unmarshal buffer, err='%w'", err)
 	}
 
 	if returnUser.Email != user.Email {
@@ -402,9 +634,13 @@ func (jc JCAPI) AddUpdateUser(op JCOp, user JCUser) (userId string, err JCError)
 }
 
 func (jc JCAPI) DeleteUser(user JCUser) JCError {
-	_, err := jc.Delete(fmt.Sprintf("/systemusers/%s", user.Id))
+	return jc.DeleteUserContext(context.Background(), user)
+}
+
+func (jc JCAPI) DeleteUserContext(ctx context.Context, user JCUser) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("/systemusers/%s", user.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete user '%s': err='%s'", user.Email, err)
+		return fmt.Errorf("ERROR: Could not delete user '%s': err='%w'", user.Email, err)
 	}
 
 	return nil
@@ -0,0 +1,345 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJCUserUnmarshalUidGid(t *testing.T) {
+	for _, body := range []string{
+		`{"_id":"a","unix_uid":2244,"unix_guid":2245}`,
+		`{"_id":"a","unix_uid":"2244","unix_guid":"2245"}`,
+	} {
+		var user JCUser
+
+		err := json.Unmarshal([]byte(body), &user)
+		if err != nil {
+			t.Fatalf("Could not unmarshal '%s', err='%s'", body, err)
+		}
+
+		if user.Uid != "2244" || user.Gid != "2245" {
+			t.Fatalf("Unexpected uid/gid decoding '%s': uid='%s' gid='%s'", body, user.Uid, user.Gid)
+		}
+	}
+}
+
+func TestJCUserUnmarshalOptionalFields(t *testing.T) {
+	body := `{"_id":"a","email":null,"unix_uid":null,"password_expiration_date":"","attributes":[{"name":"n","value":3}]}`
+
+	var user JCUser
+
+	err := json.Unmarshal([]byte(body), &user)
+	if err != nil {
+		t.Fatalf("Could not unmarshal '%s', err='%s'", body, err)
+	}
+
+	if user.Id != "a" || user.Email != "" || user.Uid != "" || !user.PasswordExpirationDate.IsZero() {
+		t.Fatalf("Unexpected user decoded from '%s': %+v", body, user)
+	}
+
+	if len(user.Attributes) != 1 || user.Attributes[0].Value != "3" {
+		t.Fatalf("Unexpected attributes decoded from '%s': %v", body, user.Attributes)
+	}
+
+	body = `{"_id":"a","password_expiration_date":"2016-05-04T03:02:01Z"}`
+
+	err = json.Unmarshal([]byte(body), &user)
+	if err != nil {
+		t.Fatalf("Could not unmarshal '%s', err='%s'", body, err)
+	}
+
+	if !user.PasswordExpirationDate.Time().Equal(time.Date(2016, 5, 4, 3, 2, 1, 0, time.UTC)) {
+		t.Fatalf("Unexpected password expiration date %s", user.PasswordExpirationDate)
+	}
+}
+
+func TestJCUserExtrasRoundTrip(t *testing.T) {
+	body := `{"_id":"a","email":"a@example.com","unix_uid":10,"mfa":{"configured":true},"middlename":"Q"}`
+
+	var user JCUser
+
+	err := json.Unmarshal([]byte(body), &user)
+	if err != nil {
+		t.Fatalf("Could not unmarshal '%s', err='%s'", body, err)
+	}
+
+	if len(user.Extras) != 2 || string(user.Extras["middlename"]) != `"Q"` {
+		t.Fatalf("Unexpected extras: %v", user.Extras)
+	}
+
+	// Extras never override the fields JCUser knows about
+	user.Extras["email"] = json.RawMessage(`"other@example.com"`)
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("Could not marshal %+v, err='%s'", user, err)
+	}
+
+	var fields map[string]interface{}
+
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		t.Fatalf("Could not unmarshal '%s', err='%s'", data, err)
+	}
+
+	if fields["email"] != "a@example.com" || fields["unix_uid"] != "10" || fields["middlename"] != "Q" {
+		t.Fatalf("Unexpected fields marshalled: %s", data)
+	}
+
+	if _, ok := fields["mfa"].(map[string]interface{}); !ok {
+		t.Fatalf("Nested extra was not written back: %s", data)
+	}
+
+	if _, ok := fields["password_expiration_date"]; ok {
+		t.Fatalf("An unset password_expiration_date should be left out: %s", data)
+	}
+}
+
+func TestAddUpdateUserLeavesExtrasOut(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","email":"a@example.com"}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	var user JCUser
+
+	err = json.Unmarshal([]byte(`{"_id":"a","email":"a@example.com","mfa":{"configured":true},"created":"2016-05-04"}`), &user)
+	if err != nil {
+		t.Fatalf("Could not unmarshal user, err='%s'", err)
+	}
+
+	if _, err = jc.AddUpdateUser(Update, user); err != nil {
+		t.Fatalf("AddUpdateUser() failed, err='%s'", err)
+	}
+
+	body, _ := ioutil.ReadAll(rt.requests[0].Body)
+	if strings.Contains(string(body), "mfa") || strings.Contains(string(body), "created") {
+		t.Fatalf("Fields read from JumpCloud were sent back: %s", body)
+	}
+
+	if len(user.Extras) != 2 {
+		t.Fatalf("AddUpdateUser() changed the extras of its argument: %v", user.Extras)
+	}
+}
+
+func TestAddUpdateUserLeavesSSHKeysOut(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","email":"a@example.com"}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	var user JCUser
+
+	err = json.Unmarshal([]byte(`{"_id":"a","email":"a@example.com","ssh_keys":[{"_id":"k1","name":"laptop","public_key":"ssh-ed25519 AAAA"}]}`), &user)
+	if err != nil || len(user.SSHKeys) != 1 {
+		t.Fatalf("Could not unmarshal user with SSH keys %+v, err='%v'", user.SSHKeys, err)
+	}
+
+	for _, op := range []JCOp{Insert, Update} {
+		if _, err = jc.AddUpdateUser(op, user); err != nil {
+			t.Fatalf("AddUpdateUser() failed, err='%s'", err)
+		}
+	}
+
+	for _, request := range rt.requests {
+		body, _ := ioutil.ReadAll(request.Body)
+		if strings.Contains(string(body), "ssh_keys") {
+			t.Fatalf("The SSH keys of the user were sent with %s: %s", request.Method, body)
+		}
+	}
+
+	if len(user.SSHKeys) != 1 {
+		t.Fatalf("AddUpdateUser() changed the SSH keys of its argument: %v", user.SSHKeys)
+	}
+}
+
+func TestGetSystemUserByIdDecodesSparseRecords(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","unix_uid":5000}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	user, err := jc.GetSystemUserById("a", false)
+	if err != nil {
+		t.Fatalf("GetSystemUserById() failed, err='%s'", err)
+	}
+
+	if user.Id != "a" || user.Uid != "5000" || user.UserName != "" {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+
+	if rt.requests[0].Method != http.MethodGet {
+		t.Fatalf("Unexpected method %s", rt.requests[0].Method)
+	}
+}
+
+//
+// newUserServer serves count users on /systemusers, and their details on /systemusers/<id>.
+// listFields is added to each list record, failIds makes the detail requests for those
+// IDs fail with a 404.
+//
+func newUserServer(t *testing.T, count int, listFields string, failIds map[string]bool, inFlight, maxInFlight, detailCalls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := strings.TrimPrefix(r.URL.Path, "/systemusers/"); id != r.URL.Path {
+			atomic.AddInt32(detailCalls, 1)
+
+			current := atomic.AddInt32(inFlight, 1)
+			defer atomic.AddInt32(inFlight, -1)
+
+			for {
+				max := atomic.LoadInt32(maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(maxInFlight, max, current) {
+					break
+				}
+			}
+
+			// Finish the detail requests out of order
+			n, _ := strconv.Atoi(strings.TrimPrefix(id, "id"))
+			time.Sleep(time.Duration(count-n) * time.Millisecond)
+
+			if failIds[id] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			fmt.Fprintf(w, `{"_id":"%s","username":"user%03d","external_dn":"cn=user%03d","external_source_type":"ldap"}`, id, n, n)
+			return
+		}
+
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var results []string
+		for i := skip; i < count && i < skip+limit; i++ {
+			results = append(results, fmt.Sprintf(`{"_id":"id%d","username":"user%03d"%s}`, i, i, listFields))
+		}
+
+		fmt.Fprintf(w, `{"totalCount":%d,"results":[%s]}`, count, strings.Join(results, ","))
+	}))
+}
+
+func TestGetSystemUsersFetchesDetailsConcurrently(t *testing.T) {
+	var inFlight, maxInFlight, detailCalls int32
+
+	ts := newUserServer(t, 40, `,"externally_managed":true`, nil, &inFlight, &maxInFlight, &detailCalls)
+	defer ts.Close()
+
+	jc, err := NewJCAPIWithOptions("key", ts.URL, WithDetailConcurrency(4))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	users, err := jc.GetSystemUsers(false)
+	if err != nil {
+		t.Fatalf("GetSystemUsers() failed, err='%s'", err)
+	}
+
+	if len(users) != 40 || detailCalls != 40 {
+		t.Fatalf("Expected 40 users and 40 detail calls, got %d and %d", len(users), detailCalls)
+	}
+
+	for idx, user := range users {
+		if user.UserName != fmt.Sprintf("user%03d", idx) || user.ExternalDN != fmt.Sprintf("cn=user%03d", idx) {
+			t.Fatalf("Unexpected user at %d: %+v", idx, user)
+		}
+	}
+
+	if maxInFlight < 2 || maxInFlight > 4 {
+		t.Fatalf("Expected between 2 and 4 concurrent detail requests, got %d", maxInFlight)
+	}
+}
+
+func TestGetSystemUsersSkipsDetailsOfCompleteRecords(t *testing.T) {
+	var inFlight, maxInFlight, detailCalls int32
+
+	ts := newUserServer(t, 150, `,"externally_managed":true,"external_dn":"","external_source_type":""`, nil, &inFlight, &maxInFlight, &detailCalls)
+	defer ts.Close()
+
+	users, err := NewJCAPI("key", ts.URL).GetSystemUsers(false)
+	if err != nil {
+		t.Fatalf("GetSystemUsers() failed, err='%s'", err)
+	}
+
+	if len(users) != 150 || detailCalls != 0 {
+		t.Fatalf("Expected 150 users and no detail calls, got %d and %d", len(users), detailCalls)
+	}
+}
+
+func TestGetSystemUsersSkipsDetailsOfPlainUsers(t *testing.T) {
+	var inFlight, maxInFlight, detailCalls int32
+
+	// The users that aren't synced from a directory have no external fields at all
+	ts := newUserServer(t, 150, `,"externally_managed":false`, nil, &inFlight, &maxInFlight, &detailCalls)
+	defer ts.Close()
+
+	users, err := NewJCAPI("key", ts.URL).GetSystemUsers(false)
+	if err != nil {
+		t.Fatalf("GetSystemUsers() failed, err='%s'", err)
+	}
+
+	if len(users) != 150 || detailCalls != 0 || users[149].UserName != "user149" {
+		t.Fatalf("Expected 150 users and no detail calls, got %d and %d", len(users), detailCalls)
+	}
+}
+
+func TestGetSystemUsersReturnsTheFirstFailure(t *testing.T) {
+	for run := 0; run < 5; run++ {
+		var inFlight, maxInFlight, detailCalls int32
+
+		ts := newUserServer(t, 30, "", map[string]bool{"id7": true, "id21": true}, &inFlight, &maxInFlight, &detailCalls)
+
+		jc, err := NewJCAPIWithOptions("key", ts.URL, WithDetailConcurrency(6))
+		if err != nil {
+			t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+		}
+
+		_, err = jc.GetSystemUsers(false)
+		ts.Close()
+
+		if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "'id7'") {
+			t.Fatalf("Expected the error for id7, got '%v'", err)
+		}
+	}
+}
+
+func TestGetSystemUserByIdReportsTagErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, TAGS_PATH) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		fmt.Fprint(w, `{"_id":"a","username":"a"}`)
+	}))
+	defer ts.Close()
+
+	jc, err := NewJCAPIWithOptions("key", ts.URL, WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	_, err = jc.GetSystemUserById("a", true)
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Expected the tags error to be returned, got '%v'", err)
+	}
+
+	_, err = jc.GetSystemById("a", true)
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Expected the tags error to be returned, got '%v'", err)
+	}
+}
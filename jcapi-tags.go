@@ -1,6 +1,7 @@
 package jcapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -15,15 +16,15 @@ type JCTagResults struct {
 }
 
 type JCTag struct {
-	Id                 string   `json:"_id,omitempty"`
-	Name               string   `json:"name"`
-	GroupName          string   `json:"groupname"`
-	Systems            []string `json:"systems"`
-	SystemUsers        []string `json:"systemusers"`
-	RegularExpressions []string `json:"regularExpressions"`
-	ExpirationTime     string   `json:"expirationTime"`
-	Expired            bool     `json:"expired"`
-	Selected           bool     `json:"selected"`
+	Id                 string    `json:"_id,omitempty"`
+	Name               string    `json:"name"`
+	GroupName          string    `json:"groupname"`
+	Systems            []string  `json:"systems"`
+	SystemUsers        []string  `json:"systemusers"`
+	RegularExpressions []string  `json:"regularExpressions"`
+	ExpirationTime     Timestamp `json:"expirationTime,omitzero"`
+	Expired            bool      `json:"expired"`
+	Selected           bool      `json:"selected"`
 
 	//
 	// For identification as an external user directory source
@@ -51,69 +52,93 @@ func GetTagNames(tags []JCTag) []string {
 	return returnVal
 }
 
+//
+// getJCTagsFromResults decodes either a single tag or a results envelope, as JumpCloud
+// sends back one or the other depending on the URL.
+//
 func getJCTagsFromResults(result []byte) (tags []JCTag, err JCError) {
-	tag := JCTag{}
+	var fields map[string]json.RawMessage
 
-	// Try unmarshalling as a single tag first, and if that works, stop there
-	err = json.Unmarshal(result, &tag)
-	if err != nil || tag.Id == "" {
-		err = nil
+	err = decodeJSON(result, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal result, err='%w'", err)
+	}
 
-		tagResults := JCTagResults{}
+	if _, isSingleTag := fields["_id"]; isSingleTag {
+		tag := JCTag{}
 
-		// Nope, must be a results array...
-		err = json.Unmarshal(result, &tagResults)
+		err = decodeJSON(result, &tag)
 		if err != nil {
-			err = fmt.Errorf("Could not unmarshal result '%s', err='%s'", string(result), err.Error())
+			return nil, fmt.Errorf("Could not unmarshal result, err='%w'", err)
 		}
 
-		tags = tagResults.Results
-	} else {
-		tags = append(tags, tag)
+		return []JCTag{tag}, nil
 	}
 
-	return
+	tagResults := JCTagResults{}
+
+	err = decodeJSON(result, &tagResults)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal result, err='%w'", err)
+	}
+
+	return tagResults.Results, nil
 }
 
 func (jc JCAPI) GetTagsByUrl(urlPath string) (tagList []JCTag, err JCError) {
+	return jc.GetTagsByUrlContext(context.Background(), urlPath)
+}
+
+func (jc JCAPI) GetTagsByUrlContext(ctx context.Context, urlPath string) (tagList []JCTag, err JCError) {
 
-	result, err := jc.DoBytes(MapJCOpToHTTP(Read), urlPath, nil)
+	result, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), urlPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: Get tags from JumpCloud failed with urlPath='%s', err='%s'", urlPath, err.Error())
+		return nil, fmt.Errorf("ERROR: Get tags from JumpCloud failed with urlPath='%s', err='%w'", urlPath, err)
 	}
 
 	tagList, err = getJCTagsFromResults(result)
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: Could not get tags from results, err='%s'", err.Error())
+		return nil, fmt.Errorf("ERROR: Could not get tags from results, err='%w'", err)
 	}
 
 	return
 }
 
 func (jc JCAPI) GetAllTags() (tagList []JCTag, err JCError) {
+	return jc.GetAllTagsContext(context.Background())
+}
+
+func (jc JCAPI) GetAllTagsContext(ctx context.Context) (tagList []JCTag, err JCError) {
+	pager := jc.NewPager(ctx, TAGS_PATH, PageOptions{Sort: "name"})
 
-	for skip := 0; skip == 0 || len(tagList) == searchLimit; skip += searchSkipInterval {
-		url := fmt.Sprintf("%s?sort=username&skip=%d&limit=%d", TAGS_PATH, skip, searchLimit)
+	for pager.Next() {
+		var tag JCTag
 
-		tags, err := jc.GetTagsByUrl(url)
+		err = pager.Decode(&tag)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR: Could not query tags, err='%s'", err)
+			return nil, err
 		}
 
-		for _, tag := range tags {
-			tagList = append(tagList, tag)
-		}
+		tagList = append(tagList, tag)
+	}
+
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("ERROR: Could not query tags, err='%w'", pager.Err())
 	}
 
 	return
 }
 
 func (jc JCAPI) GetTagByName(tagName string) (tag JCTag, err JCError) {
+	return jc.GetTagByNameContext(context.Background(), tagName)
+}
+
+func (jc JCAPI) GetTagByNameContext(ctx context.Context, tagName string) (tag JCTag, err JCError) {
 	url := fmt.Sprintf("%s/%s", TAGS_PATH, tagName)
 
-	tags, err := jc.GetTagsByUrl(url)
+	tags, err := jc.GetTagsByUrlContext(ctx, url)
 	if err != nil {
-		err = fmt.Errorf("ERROR: Could not get tags by name for '%s', url='%s', err='%s'", tagName, url, err.Error())
+		err = fmt.Errorf("ERROR: Could not get tags by name for '%s', url='%s', err='%w'", tagName, url, err)
 		return
 	}
 
@@ -128,9 +153,13 @@ func (jc JCAPI) GetTagByName(tagName string) (tag JCTag, err JCError) {
 // Add or Update a tag in place on JumpCloud
 //
 func (jc JCAPI) AddUpdateTag(op JCOp, tag JCTag) (tagId string, err JCError) {
+	return jc.AddUpdateTagContext(context.Background(), op, tag)
+}
+
+func (jc JCAPI) AddUpdateTagContext(ctx context.Context, op JCOp, tag JCTag) (tagId string, err JCError) {
 	data, err := json.Marshal(tag)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not marshal JCTag object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not marshal JCTag object, err='%w'", err)
 	}
 
 	url := TAGS_PATH
@@ -138,14 +167,14 @@ func (jc JCAPI) AddUpdateTag(op JCOp, tag JCTag) (tagId string, err JCError) {
 		url += "/" + tag.Id
 	}
 
-	result, err := jc.DoBytes(MapJCOpToHTTP(op), url, data)
+	result, err := jc.DoBytesContext(ctx, MapJCOpToHTTP(op), url, data)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not post new JCTag object, err='%s'", err)
+		return "", fmt.Errorf("ERROR: Could not post new JCTag object, err='%w'", err)
 	}
 
 	tagList, err := getJCTagsFromResults(result)
 	if err != nil {
-		return "", fmt.Errorf("ERROR: Could not get tags from results, err='%s'", err.Error())
+		return "", fmt.Errorf("ERROR: Could not get tags from results, err='%w'", err)
 	}
 
 	var resultTag JCTag
@@ -166,9 +195,13 @@ func (jc JCAPI) AddUpdateTag(op JCOp, tag JCTag) (tagId string, err JCError) {
 }
 
 func (jc JCAPI) DeleteTag(tag JCTag) JCError {
-	_, err := jc.Delete(fmt.Sprintf("%s/%s", TAGS_PATH, tag.Id))
+	return jc.DeleteTagContext(context.Background(), tag)
+}
+
+func (jc JCAPI) DeleteTagContext(ctx context.Context, tag JCTag) JCError {
+	_, err := jc.DeleteContext(ctx, fmt.Sprintf("%s/%s", TAGS_PATH, tag.Id))
 	if err != nil {
-		return fmt.Errorf("ERROR: Could not delete tag ID '%s': err='%s'", tag.Id, err)
+		return fmt.Errorf("ERROR: Could not delete tag ID '%s': err='%w'", tag.Id, err)
 	}
 
 	return nil
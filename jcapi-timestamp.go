@@ -0,0 +1,177 @@
+package jcapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The layouts, besides RFC 3339, that the API has been seen to return times in
+var timestampLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+//
+// Timestamp is a time returned by the JumpCloud API, which may be missing. The zero
+// Timestamp is no time at all, as decoded from null or "", and is written as null (or
+// left out, with the omitzero option). A Timestamp decoded from JSON is written back
+// exactly as it was received, whatever its format.
+//
+type Timestamp struct {
+	time time.Time
+	raw  string // the JSON it was decoded or parsed from, "" when built from a time.Time
+}
+
+// NewTimestamp returns the timestamp of t, the zero Timestamp for the zero time.
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{time: t}
+}
+
+//
+// ParseTimestamp parses s as RFC 3339, one of the other layouts the API uses, or a Unix
+// time in seconds or milliseconds. An empty s is the zero Timestamp. The timestamp is
+// marshalled back to s, as it was given.
+//
+func ParseTimestamp(s string) (Timestamp, error) {
+	t, err := parseTime(strings.TrimSpace(s))
+	if err != nil || t.IsZero() {
+		return Timestamp{}, err
+	}
+
+	raw, _ := json.Marshal(s)
+
+	return Timestamp{time: t, raw: string(raw)}, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unixTime(n), nil
+	}
+
+	return time.Time{}, fmt.Errorf("ERROR: Could not parse time '%s'", s)
+}
+
+// unixTime reads n as milliseconds when it is too large to be a plausible number of seconds
+func unixTime(n int64) time.Time {
+	if n > 1e11 || n < -1e11 {
+		return time.UnixMilli(n).UTC()
+	}
+
+	return time.Unix(n, 0).UTC()
+}
+
+// Time returns the time of the timestamp, the zero time when there is none.
+func (t Timestamp) Time() time.Time {
+	return t.time
+}
+
+// IsZero reports whether the timestamp holds no time.
+func (t Timestamp) IsZero() bool {
+	return t.time.IsZero()
+}
+
+// Equal reports whether both timestamps hold the same instant, or both hold none.
+func (t Timestamp) Equal(other Timestamp) bool {
+	return t.time.Equal(other.time)
+}
+
+// Age returns how long ago the timestamp was, 0 when it holds no time.
+func (t Timestamp) Age() time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+
+	return time.Since(t.time)
+}
+
+// Before reports whether the timestamp holds a time more than d ago.
+func (t Timestamp) Before(d time.Duration) bool {
+	return !t.IsZero() && t.Age() > d
+}
+
+// Within reports whether the timestamp holds a time no more than d ago.
+func (t Timestamp) Within(d time.Duration) bool {
+	return !t.IsZero() && t.Age() <= d
+}
+
+// String returns the timestamp as it was received, or in RFC 3339, and "" when it holds no time.
+func (t Timestamp) String() string {
+	if t.raw != "" {
+		var s string
+		if json.Unmarshal([]byte(t.raw), &s) == nil {
+			return s
+		}
+
+		return t.raw
+	}
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.time.Format(time.RFC3339Nano)
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.raw != "":
+		return []byte(t.raw), nil
+	case t.IsZero():
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.time.Format(time.RFC3339Nano))
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n json.Number
+		if json.Unmarshal(data, &n) != nil {
+			return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeOf(Timestamp{})}
+		}
+
+		s = n.String()
+	}
+
+	parsed, err := parseTime(strings.TrimSpace(s))
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "string " + strconv.Quote(s), Type: reflect.TypeOf(Timestamp{})}
+	}
+
+	*t = Timestamp{time: parsed, raw: string(data)}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParseTimestamp(s string) Timestamp {
+	t, err := ParseTimestamp(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestTimestampFormats(t *testing.T) {
+	expected := time.Date(2016, 5, 4, 3, 2, 1, 0, time.UTC)
+
+	for _, data := range []string{
+		`"2016-05-04T03:02:01Z"`,
+		`"2016-05-04T05:02:01+02:00"`,
+		`"2016-05-04T03:02:01.000Z"`,
+		`"2016-05-04T03:02:01"`,
+		`"2016-05-04 03:02:01"`,
+		`"Wed, 04 May 2016 03:02:01 +0000"`,
+		`1462330921`,
+		`1462330921000`,
+		`"1462330921"`,
+	} {
+		var ts Timestamp
+
+		if err := json.Unmarshal([]byte(data), &ts); err != nil {
+			t.Errorf("Could not unmarshal %s, err='%s'", data, err)
+			continue
+		}
+
+		if !ts.Time().Equal(expected) {
+			t.Errorf("Unexpected time decoded from %s: %s", data, ts.Time())
+		}
+
+		// Whatever the format, the timestamp is written back as it came
+		if out, err := json.Marshal(ts); err != nil || string(out) != data {
+			t.Errorf("Unexpected round trip of %s: '%s', err='%v'", data, out, err)
+		}
+	}
+
+	for _, data := range []string{`null`, `""`} {
+		var ts Timestamp
+
+		if err := json.Unmarshal([]byte(data), &ts); err != nil || !ts.IsZero() || ts.String() != "" {
+			t.Errorf("Expected %s to be no time, got '%s', err='%v'", data, ts, err)
+		}
+	}
+
+	for _, data := range []string{`"yesterday"`, `true`, `{}`, `12.5`} {
+		var ts Timestamp
+
+		if err := json.Unmarshal([]byte(data), &ts); err == nil {
+			t.Errorf("Expected an error decoding %s", data)
+		}
+	}
+}
+
+func TestTimestampHelpers(t *testing.T) {
+	var zero Timestamp
+
+	if zero.Age() != 0 || zero.Before(0) || zero.Within(time.Hour) || !zero.Equal(NewTimestamp(time.Time{})) {
+		t.Fatalf("The zero Timestamp should hold no time")
+	}
+
+	if data, _ := json.Marshal(zero); string(data) != "null" {
+		t.Fatalf("Unexpected zero Timestamp marshalled: %s", data)
+	}
+
+	hourAgo := NewTimestamp(time.Now().Add(-time.Hour))
+
+	if !hourAgo.Before(30*time.Minute) || hourAgo.Before(2*time.Hour) || !hourAgo.Within(2*time.Hour) || hourAgo.Age() < time.Hour {
+		t.Fatalf("Unexpected helpers for a time an hour ago, age %s", hourAgo.Age())
+	}
+
+	ts := NewTimestamp(time.Date(2016, 5, 4, 3, 2, 1, 500, time.UTC))
+	if data, _ := json.Marshal(ts); string(data) != `"2016-05-04T03:02:01.0000005Z"` || ts.String() != "2016-05-04T03:02:01.0000005Z" {
+		t.Fatalf("Unexpected Timestamp marshalled: %s", data)
+	}
+}
+
+func TestModelTimestamps(t *testing.T) {
+	var system JCSystem
+
+	err := decodeJSON([]byte(`{"_id":"a","lastContact":"2016-05-04T03:02:01.123Z","created":""}`), &system)
+	if err != nil || system.LastContact.Time().Year() != 2016 || !system.Created.IsZero() {
+		t.Fatalf("Unexpected system decoded %+v, err='%v'", system, err)
+	}
+
+	if data, _ := json.Marshal(system); !strings.Contains(string(data), `"lastContact":"2016-05-04T03:02:01.123Z"`) {
+		t.Fatalf("lastContact should be written back as received: %s", data)
+	}
+
+	var result JCCommandResult
+
+	err = decodeJSON([]byte(`{"_id":"r","requestTime":"2016-05-04T03:02:01Z","responseTime":"soon"}`), &result)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || !strings.Contains(err.Error(), "responseTime") {
+		t.Fatalf("Expected a DecodeError naming responseTime, got '%v'", err)
+	}
+
+	// Times that are not set are left out, rather than sent as the zero time
+	data, _ := json.Marshal(JCUser{UserName: "jdoe"})
+	if strings.Contains(string(data), "password_expiration_date") || strings.Contains(string(data), "password_date") {
+		t.Fatalf("Unset times should be left out: %s", data)
+	}
+
+	data, _ = json.Marshal(JCTag{Name: "t"})
+	if strings.Contains(string(data), "expirationTime") {
+		t.Fatalf("An unset expiration time should be left out: %s", data)
+	}
+}
@@ -0,0 +1,152 @@
+package jcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//
+// Request structure for resetting the TOTP (MFA) key of a user, who may be let in
+// without MFA until ExclusionUntil while enrolling a new device
+//
+type JCUserTOTPResetRequest struct {
+	Exclusion      bool   `json:"exclusion"`
+	ExclusionUntil string `json:"exclusionUntil,omitempty"`
+}
+
+// jcUserIdEmailRequest is a JCUserEmailRequest that sends only the IDs of the users
+type jcUserIdEmailRequest struct {
+	IsSelectAll bool       `json:"isSelectAll"`
+	Models      []jcUserId `json:"models"`
+}
+
+type jcUserId struct {
+	Id string `json:"_id"`
+}
+
+//
+// userAction posts request to the action URL of the user (such as "/systemusers/<id>/unlock"),
+// and returns the user as updated by it.
+//
+func (jc JCAPI) userAction(ctx context.Context, userId, action, url string, request interface{}) (user JCUser, err JCError) {
+	if userId == "" {
+		return user, fmt.Errorf("ERROR: Cannot %s a system user without its ID", action)
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return user, fmt.Errorf("ERROR: Could not marshal %s request for user ID '%s', err='%w'", action, userId, err)
+	}
+
+	_, err = jc.DoBytesContext(ctx, MapJCOpToHTTP(Insert), url, data)
+	if err != nil {
+		return user, fmt.Errorf("ERROR: Could not %s user ID '%s', err='%w'", action, userId, err)
+	}
+
+	return jc.GetSystemUserByIdContext(ctx, userId, false)
+}
+
+func (jc JCAPI) LockUser(userId string) (JCUser, JCError) {
+	return jc.LockUserContext(context.Background(), userId)
+}
+
+// LockUserContext locks the account of the user, who can't log in anywhere until unlocked.
+func (jc JCAPI) LockUserContext(ctx context.Context, userId string) (JCUser, JCError) {
+	return jc.UpdateUserFieldsContext(ctx, userId, JCUserChanges{"account_locked": true})
+}
+
+func (jc JCAPI) UnlockUser(userId string) (JCUser, JCError) {
+	return jc.UnlockUserContext(context.Background(), userId)
+}
+
+// UnlockUserContext unlocks the account of a user locked by an admin or by too many failed logins.
+func (jc JCAPI) UnlockUserContext(ctx context.Context, userId string) (JCUser, JCError) {
+	return jc.userAction(ctx, userId, "unlock", "/systemusers/"+userId+"/unlock", struct{}{})
+}
+
+func (jc JCAPI) SuspendUser(userId string, suspended bool) (JCUser, JCError) {
+	return jc.SuspendUserContext(context.Background(), userId, suspended)
+}
+
+//
+// SuspendUserContext suspends (or reinstates) the user, who keeps their accounts and
+// associations but can't use any of them while suspended.
+//
+func (jc JCAPI) SuspendUserContext(ctx context.Context, userId string, suspended bool) (JCUser, JCError) {
+	return jc.UpdateUserFieldsContext(ctx, userId, JCUserChanges{"suspended": suspended})
+}
+
+func (jc JCAPI) ExpireUserPassword(userId string) (JCUser, JCError) {
+	return jc.ExpireUserPasswordContext(context.Background(), userId)
+}
+
+// ExpireUserPasswordContext expires the password of the user, who must change it on their next login.
+func (jc JCAPI) ExpireUserPasswordContext(ctx context.Context, userId string) (JCUser, JCError) {
+	return jc.userAction(ctx, userId, "expire the password of", "/systemusers/"+userId+"/expire", struct{}{})
+}
+
+func (jc JCAPI) SendPasswordResetEmail(userId string) JCError {
+	return jc.SendPasswordResetEmailContext(context.Background(), userId)
+}
+
+//
+// SendPasswordResetEmailContext sends the user an email with a link to reset their
+// password. Only the ID of the user is sent, in the request shape of the activation
+// emails of SendUserActivationEmail().
+//
+// WARNING: The v1 API reference (https://github.com/TheJumpCloud/JumpCloudAPI#system-users)
+// documents no endpoint for this; /systemusers/resetpassword follows /systemusers/reactivate
+// and should be checked against your account before relying on it.
+//
+func (jc JCAPI) SendPasswordResetEmailContext(ctx context.Context, userId string) JCError {
+	if userId == "" {
+		return fmt.Errorf("ERROR: Cannot send a password reset email without a systemuser Id")
+	}
+
+	emailRequest := jcUserIdEmailRequest{
+		IsSelectAll: false,
+		Models:      []jcUserId{{Id: userId}},
+	}
+
+	data, err := json.Marshal(emailRequest)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not marshal password reset email request for user ID '%s', err='%w'", userId, err)
+	}
+
+	_, err = jc.DoBytesContext(ctx, MapJCOpToHTTP(Insert), "/systemusers/resetpassword", data)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not send a password reset email to user ID '%s', err='%w'", userId, err)
+	}
+
+	return nil
+}
+
+func (jc JCAPI) ResetUserMFA(userId string, exclusionUntil time.Time) (JCUser, JCError) {
+	return jc.ResetUserMFAContext(context.Background(), userId, exclusionUntil)
+}
+
+//
+// ResetUserMFAContext resets the TOTP key of the user, who has to enroll their MFA device
+// again. Unless exclusionUntil is zero, the user may log in without MFA until then.
+//
+func (jc JCAPI) ResetUserMFAContext(ctx context.Context, userId string, exclusionUntil time.Time) (JCUser, JCError) {
+	request := JCUserTOTPResetRequest{}
+
+	if !exclusionUntil.IsZero() {
+		request.Exclusion = true
+		request.ExclusionUntil = exclusionUntil.UTC().Format(time.RFC3339)
+	}
+
+	return jc.userAction(ctx, userId, "reset the MFA of", "/systemusers/"+userId+"/resetmfa", request)
+}
+
+func (jc JCAPI) EnableUserPortalMFA(userId string, enabled bool) (JCUser, JCError) {
+	return jc.EnableUserPortalMFAContext(context.Background(), userId, enabled)
+}
+
+// EnableUserPortalMFAContext requires (or stops requiring) MFA when the user logs in to the user portal.
+func (jc JCAPI) EnableUserPortalMFAContext(ctx context.Context, userId string, enabled bool) (JCUser, JCError) {
+	return jc.UpdateUserFieldsContext(ctx, userId, JCUserChanges{"enable_user_portal_multifactor": enabled})
+}
@@ -0,0 +1,121 @@
+package jcapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserFieldActions(t *testing.T) {
+	server, jc := newObjectServer(t, map[string]map[string]interface{}{
+		"/systemusers/a": {"_id": "a", "username": "jdoe", "sudo": true},
+	})
+
+	user, err := jc.LockUser("a")
+	if err != nil || !user.AccountLocked || !user.Sudo {
+		t.Fatalf("LockUser() returned %+v, err='%v'", user, err)
+	}
+
+	user, err = jc.SuspendUser("a", true)
+	if err != nil || !user.Suspended {
+		t.Fatalf("SuspendUser() returned %+v, err='%v'", user, err)
+	}
+
+	user, err = jc.EnableUserPortalMFA("a", true)
+	if err != nil || !user.EnableUserPortalMultifactor {
+		t.Fatalf("EnableUserPortalMFA() returned %+v, err='%v'", user, err)
+	}
+
+	user, err = jc.SuspendUser("a", false)
+	if err != nil || user.Suspended || !user.AccountLocked {
+		t.Fatalf("SuspendUser() returned %+v, err='%v'", user, err)
+	}
+
+	expected := []string{
+		`/systemusers/a {"account_locked":true}`,
+		`/systemusers/a {"suspended":true}`,
+		`/systemusers/a {"enable_user_portal_multifactor":true}`,
+		`/systemusers/a {"suspended":false}`,
+	}
+
+	if strings.Join(server.puts, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected updates %v", server.puts)
+	}
+}
+
+func TestUserPostActions(t *testing.T) {
+	var requests []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Write([]byte(`{"_id":"a","username":"jdoe","password_expired":true,"totp_enabled":false}`))
+	}))
+	defer ts.Close()
+
+	jc := NewJCAPI("key", ts.URL)
+
+	user, err := jc.UnlockUser("a")
+	if err != nil || user.Id != "a" {
+		t.Fatalf("UnlockUser() returned %+v, err='%v'", user, err)
+	}
+
+	user, err = jc.ExpireUserPassword("a")
+	if err != nil || !user.PasswordExpired {
+		t.Fatalf("ExpireUserPassword() returned %+v, err='%v'", user, err)
+	}
+
+	_, err = jc.ResetUserMFA("a", time.Time{})
+	if err != nil {
+		t.Fatalf("ResetUserMFA() failed, err='%s'", err)
+	}
+
+	_, err = jc.ResetUserMFA("a", time.Date(2016, 5, 4, 3, 2, 1, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ResetUserMFA() failed, err='%s'", err)
+	}
+
+	expected := []string{
+		"POST /systemusers/a/unlock {}",
+		"GET /systemusers/a ",
+		"POST /systemusers/a/expire {}",
+		"GET /systemusers/a ",
+		`POST /systemusers/a/resetmfa {"exclusion":false}`,
+		"GET /systemusers/a ",
+		`POST /systemusers/a/resetmfa {"exclusion":true,"exclusionUntil":"2016-05-04T03:02:01Z"}`,
+		"GET /systemusers/a ",
+	}
+
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	requests = nil
+
+	err = jc.SendPasswordResetEmail("a")
+	if err != nil {
+		t.Fatalf("SendPasswordResetEmail() failed, err='%s'", err)
+	}
+
+	// Only the ID of the user is sent, nothing read from JumpCloud
+	if s := strings.Join(requests, "\n"); s != `POST /systemusers/resetpassword {"isSelectAll":false,"models":[{"_id":"a"}]}` {
+		t.Fatalf("Unexpected requests:\n%s", s)
+	}
+
+	if err = jc.SendPasswordResetEmail(""); err == nil {
+		t.Fatalf("Expected an error sending a password reset email without user ID")
+	}
+
+	if _, err = jc.UnlockUser(""); err == nil {
+		t.Fatalf("Expected an error unlocking a user without ID")
+	}
+}
@@ -0,0 +1,219 @@
+package jcapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// JCUserChanges is a partial update of a system user: it maps the JSON name of each
+// field to change (e.g. "sudo" or "attributes") onto its new value. Build one with
+// DiffUsers() or NewUserChanges(), or by hand, and send it with UpdateUserFields().
+//
+type JCUserChanges map[string]interface{}
+
+// Fields returns the sorted names of the fields changed.
+func (changes JCUserChanges) Fields() []string {
+	names := make([]string, 0, len(changes))
+
+	for name := range changes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+//
+// jsonFieldValues returns the value of every field of the struct v that is sent to
+// JumpCloud, keyed on its JSON name. Unlike json.Marshal(), it keeps the empty values
+// of the omitempty fields, so that clearing one of them shows up as a change.
+//
+func jsonFieldValues(v interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	value := reflect.Indirect(reflect.ValueOf(v))
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			values[name] = value.Field(i).Interface()
+		}
+	}
+
+	return values
+}
+
+//
+// diffFields returns the fields of the struct to that differ from those of from, keyed
+// on their JSON name, leaving out its ID.
+//
+func diffFields(from, to interface{}) map[string]interface{} {
+	changes := make(map[string]interface{})
+
+	fromValues := jsonFieldValues(from)
+
+	for name, value := range jsonFieldValues(to) {
+		if name != "_id" && !sameFieldValue(fromValues[name], value) {
+			changes[name] = value
+		}
+	}
+
+	return changes
+}
+
+func sameFieldValue(a, b interface{}) bool {
+	if ta, ok := a.(Timestamp); ok {
+		return ta.Equal(b.(Timestamp))
+	}
+
+	// A nil and an empty list are the same thing to JumpCloud
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Slice && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+//
+// DiffUsers returns the changes that turn the system user from into to. The ID is never
+// part of the changes, and the password only when to has a new one, as JumpCloud
+// never sends it back. Fields in Extras are compared too, but a field dropped from
+// to.Extras is not cleared on JumpCloud.
+//
+func DiffUsers(from, to JCUser) JCUserChanges {
+	changes := JCUserChanges(diffFields(from, to))
+
+	// JumpCloud never sends the password back, so only a new one is a change
+	if to.Password == "" || to.Password == from.Password {
+		delete(changes, "password")
+	}
+
+	for name, value := range to.Extras {
+		if _, known := jcUserKnownFields[name]; known {
+			continue
+		}
+
+		if !bytes.Equal(from.Extras[name], value) {
+			changes[name] = value
+		}
+	}
+
+	return changes
+}
+
+//
+// NewUserChanges returns a change set that sets the named fields (JSON names, such as
+// "attributes") to their value in user, whether or not they changed.
+//
+func NewUserChanges(user JCUser, fields ...string) (JCUserChanges, error) {
+	changes := make(JCUserChanges)
+	values := jsonFieldValues(user)
+
+	for _, name := range fields {
+		if value, ok := values[name]; ok {
+			changes[name] = value
+		} else if value, ok := user.Extras[name]; ok {
+			changes[name] = value
+		} else {
+			return nil, fmt.Errorf("ERROR: '%s' is not a field of a system user", name)
+		}
+	}
+
+	return changes, nil
+}
+
+func (jc JCAPI) UpdateUserFields(userId string, changes JCUserChanges) (JCUser, JCError) {
+	return jc.UpdateUserFieldsContext(context.Background(), userId, changes)
+}
+
+//
+// UpdateUserFieldsContext sends only the given changes to the system user, leaving every
+// other field of the user as it is on JumpCloud, and returns the updated user. Unlike
+// AddUpdateUser(Update, ...), it can't reset fields such as sudo or activated by accident.
+// A new password is checked against the password policy of jc first, as in AddUpdateUser().
+//
+func (jc JCAPI) UpdateUserFieldsContext(ctx context.Context, userId string, changes JCUserChanges) (user JCUser, err JCError) {
+	if userId == "" {
+		return user, fmt.Errorf("ERROR: Cannot update a system user without its ID")
+	}
+
+	fields := make(map[string]interface{}, len(changes))
+
+	for name, value := range changes {
+		if name != "_id" {
+			fields[name] = value
+		}
+	}
+
+	// Nothing to change, don't bother JumpCloud with an empty update
+	if len(fields) == 0 {
+		return jc.GetSystemUserByIdContext(ctx, userId, false)
+	}
+
+	if _, ok := fields["password"]; ok {
+		err = jc.validatePasswordChange(ctx, userId, fields)
+		if err != nil {
+			return user, err
+		}
+
+		if _, ok := fields["password_date"]; !ok {
+			fields["password_date"] = NewTimestamp(time.Now().Truncate(time.Second))
+		}
+	}
+
+	err = jc.putFields(ctx, "/systemusers/"+userId, fields, &user)
+	if err != nil {
+		return user, fmt.Errorf("ERROR: Could not update fields %v of user ID '%s', err='%w'", changes.Fields(), userId, err)
+	}
+
+	return
+}
+
+//
+// validatePasswordChange checks the password of fields against the password policy of
+// jc, with the username and email of fields, or else those of the user on JumpCloud. The
+// user is only read when the password passes without them.
+//
+func (jc JCAPI) validatePasswordChange(ctx context.Context, userId string, fields map[string]interface{}) error {
+	if jc.passwordPolicy == nil {
+		return nil
+	}
+
+	password, ok := fields["password"].(string)
+	if !ok {
+		return fmt.Errorf("ERROR: The password of user ID '%s' must be a string, not %#v", userId, fields["password"])
+	}
+
+	user := JCUser{Password: password}
+	user.UserName, _ = fields["username"].(string)
+	user.Email, _ = fields["email"].(string)
+
+	err := jc.passwordPolicy.Validate(user)
+	if err != nil || !jc.passwordPolicy.RejectUserInfo || (user.UserName != "" && user.Email != "") {
+		return err
+	}
+
+	current, err := jc.GetSystemUserByIdContext(ctx, userId, false)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not read user ID '%s' to check its new password, err='%w'", userId, err)
+	}
+
+	if user.UserName == "" {
+		user.UserName = current.UserName
+	}
+
+	if user.Email == "" {
+		user.Email = current.Email
+	}
+
+	return jc.passwordPolicy.Validate(user)
+}
@@ -0,0 +1,124 @@
+package jcapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffUsers(t *testing.T) {
+	from := JCUser{
+		Id:         "a",
+		UserName:   "jdoe",
+		Email:      "jdoe@example.com",
+		Password:   "secret",
+		Activated:  true,
+		Sudo:       true,
+		Uid:        "5000",
+		Attributes: []JCUserAttribute{},
+		Extras:     map[string]json.RawMessage{"middlename": json.RawMessage(`"Q"`)},
+	}
+
+	if changes := DiffUsers(from, from); len(changes) != 0 {
+		t.Fatalf("Expected no changes between identical users, got %v", changes)
+	}
+
+	to := from
+	to.Id = "b"
+	to.Attributes = nil
+	to.Password = ""
+	to.Email = "john.doe@example.com"
+	to.Uid = "5001"
+	to.Extras = map[string]json.RawMessage{"middlename": json.RawMessage(`"R"`)}
+
+	changes := DiffUsers(from, to)
+
+	expected := JCUserChanges{"email": "john.doe@example.com", "unix_uid": "5001", "middlename": json.RawMessage(`"R"`)}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Unexpected changes %v, want %v", changes, expected)
+	}
+
+	to = from
+	to.Sudo = false
+	to.Password = "new secret"
+	to.Attributes = []JCUserAttribute{{"dept", "sales"}}
+
+	if fields := DiffUsers(from, to).Fields(); strings.Join(fields, " ") != "attributes password sudo" {
+		t.Fatalf("Unexpected fields changed: %v", fields)
+	}
+}
+
+func TestNewUserChanges(t *testing.T) {
+	user := JCUser{Sudo: false, Extras: map[string]json.RawMessage{"middlename": json.RawMessage(`"Q"`)}}
+
+	changes, err := NewUserChanges(user, "sudo", "middlename")
+	if err != nil {
+		t.Fatalf("NewUserChanges() failed, err='%s'", err)
+	}
+
+	if changes["sudo"] != false || string(changes["middlename"].(json.RawMessage)) != `"Q"` {
+		t.Fatalf("Unexpected changes %v", changes)
+	}
+
+	if _, err = NewUserChanges(user, "sudo", "Sudo"); err == nil {
+		t.Fatalf("Expected an error for an unknown field")
+	}
+}
+
+func TestUpdateUserFieldsSendsOnlyTheChanges(t *testing.T) {
+	rt := &recordingTransport{body: `{"_id":"a","username":"jdoe","sudo":true,"attributes":[{"name":"dept","value":"sales"}]}`}
+
+	jc, err := NewJCAPIWithOptions("key", "https://jc.example.com/api", WithTransport(rt))
+	if err != nil {
+		t.Fatalf("NewJCAPIWithOptions() failed, err='%s'", err)
+	}
+
+	user, err := jc.UpdateUserFields("a", JCUserChanges{"_id": "b", "attributes": []JCUserAttribute{{"dept", "sales"}}})
+	if err != nil {
+		t.Fatalf("UpdateUserFields() failed, err='%s'", err)
+	}
+
+	if !user.Sudo || len(user.Attributes) != 1 {
+		t.Fatalf("Unexpected user returned: %+v", user)
+	}
+
+	request := rt.requests[0]
+	body, _ := ioutil.ReadAll(request.Body)
+
+	if request.Method != http.MethodPut || request.URL.Path != "/api/systemusers/a" {
+		t.Fatalf("Unexpected request %s %s", request.Method, request.URL)
+	}
+
+	if string(body) != `{"attributes":[{"name":"dept","value":"sales"}]}` {
+		t.Fatalf("Unexpected body '%s'", body)
+	}
+
+	// A new password goes with its date
+	_, err = jc.UpdateUserFields("a", JCUserChanges{"password": "new secret"})
+	if err != nil {
+		t.Fatalf("UpdateUserFields() failed, err='%s'", err)
+	}
+
+	var fields map[string]interface{}
+
+	body, _ = ioutil.ReadAll(rt.requests[1].Body)
+	if err := json.Unmarshal(body, &fields); err != nil || len(fields) != 2 || fields["password_date"] == nil {
+		t.Fatalf("Unexpected body '%s'", body)
+	}
+
+	// In the same format as AddUpdateUser() sends it
+	date, _ := fields["password_date"].(string)
+	if parsed, err := time.Parse(time.RFC3339, date); err != nil || parsed.Nanosecond() != 0 || time.Since(parsed) > time.Minute {
+		t.Fatalf("Unexpected password date '%s'", date)
+	}
+
+	// Nothing to change only reads the user back
+	_, err = jc.UpdateUserFields("a", JCUserChanges{})
+	if err != nil || rt.requests[2].Method != http.MethodGet {
+		t.Fatalf("Expected a GET for an empty change set, err='%v'", err)
+	}
+}
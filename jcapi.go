@@ -2,7 +2,7 @@ package jcapi
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,7 +10,6 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
-	"time"
 )
 
 const (
@@ -31,11 +30,22 @@ const (
 type JCAPI struct {
 	ApiKey  string
 	UrlBase string
+
+	client      *http.Client // shared by every request, see NewJCAPIWithOptions()
+	userAgent   string
+	retryPolicy *RetryPolicy // nil disables retries
+	rateLimiter *RateLimiter // nil disables client-side rate limiting
+
+	responseHook func(*ResponseMeta)
+
+	detailConcurrency int // 0 uses DefaultDetailConcurrency
+	modifyAttempts    int // 0 uses DefaultModifyAttempts
+
+	passwordPolicy *PasswordPolicy // nil sends any password
 }
 
 const (
-	searchLimit        int = 100
-	searchSkipInterval int = 100
+	searchLimit int = 100
 )
 
 const (
@@ -57,185 +67,206 @@ func (e *errorString) Error() string {
 }
 
 func NewJCAPI(apiKey string, urlBase string) JCAPI {
+	retryPolicy := DefaultRetryPolicy
+
 	return JCAPI{
-		ApiKey:  apiKey,
-		UrlBase: urlBase,
+		ApiKey:      apiKey,
+		UrlBase:     urlBase,
+		retryPolicy: &retryPolicy,
 	}
 }
 
-func buildJSONStringArray(field string, s []string) string {
-	returnVal := "["
+func (jc JCAPI) setHeader(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-api-key", jc.ApiKey)
 
-	if s != nil {
-		afterFirst := false
+	if jc.userAgent != "" {
+		req.Header.Set("User-Agent", jc.userAgent)
+	}
+}
 
-		for _, val := range s {
-			if afterFirst {
-				returnVal += ","
-			}
+//
+// send builds the request for op/urlQuery and sends it through the shared client,
+// retrying it according to the retry policy. Every call to JumpCloud goes through
+// here, the caller owns the response body.
+//
+func (jc JCAPI) send(ctx context.Context, op, urlQuery string, data []byte) (*http.Response, error) {
+	fullUrl := jc.UrlBase + urlQuery
 
-			returnVal += "\"" + val + "\""
+	for attempt := 1; ; attempt++ {
+		// if there is no data, we should send a nil body, not an empty one:
+		var body io.Reader
+		if len(data) > 0 {
+			body = bytes.NewReader(data)
+		}
 
-			afterFirst = true
+		req, err := http.NewRequestWithContext(ctx, op, fullUrl, body)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Could not build search request: '%w'", err)
 		}
-	}
-	returnVal += "]"
 
-	return "\"" + field + "\":" + returnVal
-}
+		jc.setHeader(req)
 
-func buildJSONKeyValuePair(key, value string) string {
-	return "\"" + key + "\":\"" + value + "\""
-}
+		if jc.rateLimiter != nil {
+			if err := jc.rateLimiter.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("ERROR: Rate limiter wait failed, err='%w'", err)
+			}
+		}
 
-func buildJSONKeyValueBoolPair(key string, value bool) string {
-	if value == true {
-		return "\"" + key + "\":\"true\""
-	} else {
-		return "\"" + key + "\":\"false\""
-	}
+		resp, err := jc.httpClient().Do(req)
 
-}
+		if jc.responseHook != nil && resp != nil {
+			jc.responseHook(newResponseMeta(resp))
+		}
 
-func getTimeString() string {
-	t := time.Now()
+		if jc.rateLimiter != nil && resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				wait, _ := retryAfter(resp)
+				jc.rateLimiter.throttled(wait)
+			} else {
+				jc.rateLimiter.succeeded()
+			}
+		}
 
-	return t.Format(time.RFC3339)
-}
+		// Never retry once the caller has given up
+		if jc.retryPolicy == nil || ctx.Err() != nil || !jc.retryPolicy.shouldRetry(op, urlQuery, attempt, resp, err) {
+			if err != nil {
+				return nil, newTransportError(op, urlQuery, err)
+			}
 
-func (jc JCAPI) emailFilter(email string) []byte {
+			return resp, nil
+		}
 
-	//
-	// Ideally, this would be generalized to take a map[string]string,
-	// that doesn't elicit the correct JSON output for the JumpCloud
-	// filters in json.Marshal()
-	//
-	return []byte(fmt.Sprintf("{\"filter\": [{\"email\" : \"%s\"}]}", email))
-}
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait = jc.retryPolicy.backoff(attempt)
+		}
 
-//being lazy; copy paste
-func (jc JCAPI) hostnameFilter(hostname string) []byte {
+		if jc.retryPolicy.OnRetry != nil {
+			retry := RetryAttempt{
+				Method:  op,
+				Path:    urlQuery,
+				Attempt: attempt,
+				Err:     err,
+				Wait:    wait,
+			}
+			if resp != nil {
+				retry.StatusCode = resp.StatusCode
+			}
 
-	//
-	// Ideally, this would be generalized to take a map[string]string,
-	// that doesn't elicit the correct JSON output for the JumpCloud
-	// filters in json.Marshal()
-	//
-	return []byte(fmt.Sprintf("{\"filter\": [{\"hostname\" : \"%s\"}]}", hostname))
-}
+			jc.retryPolicy.OnRetry(retry)
+		}
 
-func (jc JCAPI) setHeader(req *http.Request) {
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("x-api-key", jc.ApiKey)
-}
+		if resp != nil {
+			// Drain the body so that the connection can be reused for the next attempt
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, responseSize))
+			resp.Body.Close()
+		}
 
-func (jc JCAPI) Post(url string, data []byte) (interface{}, JCError) {
-	return jc.Do(MapJCOpToHTTP(Insert), url, data)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, newTransportError(op, urlQuery, err)
+		}
+	}
 }
 
-func (jc JCAPI) Put(url string, data []byte) (interface{}, JCError) {
-	return jc.Do(MapJCOpToHTTP(Update), url, data)
+func (jc JCAPI) Post(url string, data []byte) ([]byte, JCError) {
+	return jc.PostContext(context.Background(), url, data)
 }
 
-func (jc JCAPI) Delete(url string) (interface{}, JCError) {
-	return jc.Do(MapJCOpToHTTP(Delete), url, nil)
+func (jc JCAPI) PostContext(ctx context.Context, url string, data []byte) ([]byte, JCError) {
+	return jc.DoBytesContext(ctx, MapJCOpToHTTP(Insert), url, data)
 }
 
-func (jc JCAPI) Get(url string) (interface{}, JCError) {
-	return jc.Do(MapJCOpToHTTP(Read), url, nil)
+func (jc JCAPI) Put(url string, data []byte) ([]byte, JCError) {
+	return jc.PutContext(context.Background(), url, data)
 }
 
-func (jc JCAPI) List(url string) (interface{}, JCError) {
-	return jc.Do(MapJCOpToHTTP(List), url, nil)
+func (jc JCAPI) PutContext(ctx context.Context, url string, data []byte) ([]byte, JCError) {
+	return jc.DoBytesContext(ctx, MapJCOpToHTTP(Update), url, data)
 }
 
-//
-// DEPRECATED: This version of Do() will be replaced by the code in DoBytes()
-// in the future, when the jcapi-systemuser issue is corrected to allow for marshalling
-// and unmarshalling using the same object.
-//
-func (jc JCAPI) Do(op, url string, data []byte) (interface{}, JCError) {
-	var returnVal interface{}
-
-	fullUrl := jc.UrlBase + url
-
-	client := &http.Client{}
-
-	// if there is no data, we should send a nil body, not an empty one:
-	var body io.Reader
-	if len(data) > 0 {
-		body = bytes.NewReader(data)
-	}
-
-	req, err := http.NewRequest(op, fullUrl, body)
-	if err != nil {
-		return returnVal, fmt.Errorf("ERROR: Could not build search request: '%s'", err)
-	}
-
-	jc.setHeader(req)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return returnVal, fmt.Errorf("ERROR: client.Do() failed, err='%s'", err)
-	}
+func (jc JCAPI) Delete(url string) ([]byte, JCError) {
+	return jc.DeleteContext(context.Background(), url)
+}
 
-	defer resp.Body.Close()
+func (jc JCAPI) DeleteContext(ctx context.Context, url string) ([]byte, JCError) {
+	return jc.DoBytesContext(ctx, MapJCOpToHTTP(Delete), url, nil)
+}
 
-	if resp.Status != "200 OK" {
-		return returnVal, fmt.Errorf("JumpCloud HTTP response status='%s'", resp.Status)
-	}
+func (jc JCAPI) Get(url string) ([]byte, JCError) {
+	return jc.GetContext(context.Background(), url)
+}
 
-	buffer, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return returnVal, fmt.Errorf("ERROR: Could not read the response body, err='%s'", err)
-	}
+func (jc JCAPI) GetContext(ctx context.Context, url string) ([]byte, JCError) {
+	return jc.DoBytesContext(ctx, MapJCOpToHTTP(Read), url, nil)
+}
 
-	err = json.Unmarshal(buffer, &returnVal)
-	if err != nil {
-		return returnVal, fmt.Errorf("ERROR: Could not Unmarshal JSON response, err='%s'", err)
-	}
+func (jc JCAPI) List(url string) ([]byte, JCError) {
+	return jc.ListContext(context.Background(), url)
+}
 
-	return returnVal, err
+func (jc JCAPI) ListContext(ctx context.Context, url string) ([]byte, JCError) {
+	return jc.DoBytesContext(ctx, MapJCOpToHTTP(List), url, nil)
 }
 
 func (jc JCAPI) DoBytes(op, urlQuery string, data []byte) ([]byte, JCError) {
+	return jc.DoBytesContext(context.Background(), op, urlQuery, data)
+}
 
-	fullUrl := jc.UrlBase + urlQuery
-
-	client := &http.Client{}
+//
+// DoBytesContext is the context-aware form of DoBytes(). The context is attached
+// to the underlying HTTP request, so cancelling it aborts the call in flight.
+//
+func (jc JCAPI) DoBytesContext(ctx context.Context, op, urlQuery string, data []byte) ([]byte, JCError) {
+	return jc.doBytes(ctx, op, urlQuery, data, nil)
+}
 
-	// if there is no data, we should send a nil body, not an empty one:
-	var body io.Reader
-	if len(data) > 0 {
-		body = bytes.NewReader(data)
-	}
+//
+// doBytes sends the request and returns the body of a successful (2xx) response,
+// which is empty for a 204 No Content. If meta isn't nil, it is set to the metadata
+// of the final response.
+//
+func (jc JCAPI) doBytes(ctx context.Context, op, urlQuery string, data []byte, meta **ResponseMeta) (buffer []byte, err JCError) {
+	err = jc.doStream(ctx, op, urlQuery, data, meta, func(body io.Reader) error {
+		var err2 error
 
-	req, err := http.NewRequest(op, fullUrl, body)
+		buffer, err2 = ioutil.ReadAll(body)
+		if err2 != nil {
+			return fmt.Errorf("ERROR: Could not read the response body, err='%w'", err2)
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("ERROR: Could not build search request: '%s'", err)
-	}
+		return nil
+	})
 
-	jc.setHeader(req)
+	return
+}
 
-	resp, err := client.Do(req)
+//
+// doStream sends the request and hands the body of a successful (2xx) response to
+// read as it arrives, instead of buffering it. read is not called for a 204 No Content.
+//
+func (jc JCAPI) doStream(ctx context.Context, op, urlQuery string, data []byte, meta **ResponseMeta, read func(io.Reader) error) JCError {
+	resp, err := jc.send(ctx, op, urlQuery, data)
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: client.Do() failed, err='%s'", err)
+		return err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.Status != "200 OK" {
-		return nil, fmt.Errorf("JumpCloud HTTP response status='%s'", resp.Status)
+	if meta != nil {
+		*meta = newResponseMeta(resp)
 	}
 
-	buffer, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ERROR: Could not read the response body, err='%s'", err)
+	if !isSuccessStatus(resp.StatusCode) {
+		return newResponseError(op, urlQuery, resp)
+	}
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
 	}
 
-	return buffer, err
+	return read(resp.Body)
 }
 
 // Add all the tags of which the user is a part to the JCUser object
@@ -282,43 +313,6 @@ func MapJCOpToHTTP(op JCOp) string {
 //
 // Interface Conversion Helper Functions
 //
-func extractStringArray(input []interface{}) []string {
-	var returnVal []string
-
-	for _, str := range input {
-		returnVal = append(returnVal, str.(string))
-	}
-
-	return returnVal
-}
-
-func getStringOrNil(input interface{}) (s string) {
-	switch input.(type) {
-	case string:
-		s = input.(string)
-	}
-
-	return
-}
-
-func getUint16OrNil(input interface{}) (i uint16) {
-	switch input.(type) {
-	case uint16:
-		i = input.(uint16)
-	}
-
-	return
-}
-
-func getIntOrNil(input interface{}) (i int) {
-	switch input.(type) {
-	case int:
-		i = input.(int)
-	}
-
-	return
-}
-
 func GetTrueOrFalse(input interface{}) bool {
 	returnVal := false
 
@@ -399,7 +393,7 @@ func FindObjectByStringRegex(sourceArray []interface{}, fieldName string, regex
 
 	r, err := regexp.Compile(regex)
 	if err != nil {
-		err = fmt.Errorf("Could not compile regex for '%s', err='%s'", regex, err.Error())
+		err = fmt.Errorf("Could not compile regex for '%s', err='%w'", regex, err)
 		return
 	}
 
@@ -359,7 +359,7 @@ func MakeIDSource() JCIDSource {
 		Type:           "Active Directory",
 		Version:        "1.0.0",
 		IpAddress:      "127.0.0.1",
-		LastUpdateTime: "2014-10-14 23:34:33",
+		LastUpdateTime: mustParseTimestamp("2014-10-14 23:34:33"),
 		DN:             "CN=JumpCloud;CN=Users;DC=jumpcloud;DC=com",
 		Active:         true,
 	}
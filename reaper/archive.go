@@ -0,0 +1,119 @@
+package reaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+//
+// Snapshot is the archived copy of a system the reaper quarantined, as it was when it was
+// quarantined or, once deleted, right before it was. The times record where the system
+// is in the process: quarantined, then either released or deleted.
+//
+type Snapshot struct {
+	System        jcapi.JCSystem `json:"system"`
+	QuarantinedAt time.Time      `json:"quarantinedAt"`
+	ReleasedAt    time.Time      `json:"releasedAt,omitzero"`
+	DeletedAt     time.Time      `json:"deletedAt,omitzero"`
+}
+
+// inQuarantine reports whether the snapshot is of a system that is still quarantined
+func (snapshot Snapshot) inQuarantine() bool {
+	return !snapshot.QuarantinedAt.IsZero() && snapshot.ReleasedAt.IsZero() && snapshot.DeletedAt.IsZero()
+}
+
+//
+// Archive stores the latest snapshot of each system, by system ID. Load returns false
+// when there is no snapshot of the system.
+//
+type Archive interface {
+	Save(snapshot Snapshot) error
+	Load(systemId string) (Snapshot, bool, error)
+}
+
+// DirArchive is an Archive keeping each snapshot in a JSON file named after the system ID
+type DirArchive struct {
+	dir string
+}
+
+// NewDirArchive returns the archive in dir, creating dir if needed.
+func NewDirArchive(dir string) (*DirArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("ERROR: Could not create archive directory '%s', err='%w'", dir, err)
+	}
+
+	return &DirArchive{dir: dir}, nil
+}
+
+func (archive *DirArchive) path(systemId string) (string, error) {
+	if systemId == "" || strings.HasPrefix(systemId, ".") || strings.ContainsAny(systemId, `/\`) {
+		return "", fmt.Errorf("ERROR: Invalid system ID '%s'", systemId)
+	}
+
+	return filepath.Join(archive.dir, systemId+".json"), nil
+}
+
+// Save writes the snapshot over the previous one of the same system, if any.
+func (archive *DirArchive) Save(snapshot Snapshot) error {
+	path, err := archive.path(snapshot.System.Id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not marshal snapshot of system '%s', err='%w'", snapshot.System.Id, err)
+	}
+
+	// Write a new file and rename it, so a failure never leaves a partial snapshot
+	file, err := ioutil.TempFile(archive.dir, ".snapshot-")
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not create snapshot file, err='%w'", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not save snapshot of system '%s', err='%w'", snapshot.System.Id, err)
+	}
+
+	return nil
+}
+
+func (archive *DirArchive) Load(systemId string) (Snapshot, bool, error) {
+	path, err := archive.path(systemId)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Snapshot{}, false, nil
+	} else if err != nil {
+		return Snapshot{}, false, fmt.Errorf("ERROR: Could not read snapshot of system '%s', err='%w'", systemId, err)
+	}
+
+	var snapshot Snapshot
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, false, fmt.Errorf("ERROR: Could not unmarshal snapshot of system '%s', err='%w'", systemId, err)
+	}
+
+	return snapshot, true, nil
+}
@@ -0,0 +1,125 @@
+//
+// Package reaper removes the systems that stopped reporting to JumpCloud, in steps that
+// can be undone until the last one: a system a policy selects is first saved to a local
+// archive and put in a quarantine tag, and only deleted on a later run, once it has been
+// in quarantine for the grace period of the policy. Every run returns an audit report.
+//
+package reaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+const (
+	// The tag systems are put in before they are deleted, unless changed with Policy.QuarantineTag
+	DefaultQuarantineTag string = "reaper-quarantine"
+)
+
+//
+// Policy selects the systems to reap. A system is selected when its agent is inactive
+// and hasn't made contact in InactiveDays, it runs one of OS (any OS when empty) and not
+// one of ExcludeOS, and it isn't in any of ExcludeTags. A selected system is deleted
+// once it has been in quarantine for GraceDays, at most MaxDeletions per run.
+//
+// A policy is usually read from a JSON file, with LoadPolicy().
+//
+type Policy struct {
+	InactiveDays int      `json:"inactiveDays"`
+	OS           []string `json:"os,omitempty"` // such as "Ubuntu" or "Windows", compared exactly
+	ExcludeOS    []string `json:"excludeOS,omitempty"`
+	ExcludeTags  []string `json:"excludeTags,omitempty"` // tag names
+
+	GraceDays     int    `json:"graceDays"`              // 0 deletes the systems on the run after they are quarantined
+	MaxDeletions  int    `json:"maxDeletions,omitempty"` // 0 for no limit
+	QuarantineTag string `json:"quarantineTag,omitempty"`
+}
+
+// LoadPolicy reads a policy in JSON from r, and validates it.
+func LoadPolicy(r io.Reader) (Policy, error) {
+	var policy Policy
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&policy); err != nil {
+		return Policy{}, fmt.Errorf("ERROR: Could not read policy, err='%w'", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+
+	return policy, nil
+}
+
+// Validate returns an error when the policy could select systems that are still in use.
+func (policy Policy) Validate() error {
+	if policy.InactiveDays < 1 {
+		return fmt.Errorf("ERROR: The policy must select systems inactive for at least a day, not %d", policy.InactiveDays)
+	}
+
+	if policy.GraceDays < 0 || policy.MaxDeletions < 0 {
+		return fmt.Errorf("ERROR: The grace period and maximum deletions of the policy can't be negative")
+	}
+
+	for _, name := range policy.ExcludeTags {
+		if name == policy.quarantineTag() {
+			return fmt.Errorf("ERROR: The policy can't exclude its own quarantine tag '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+func (policy Policy) quarantineTag() string {
+	if policy.QuarantineTag == "" {
+		return DefaultQuarantineTag
+	}
+
+	return policy.QuarantineTag
+}
+
+// Query returns the query of the systems inactive for the days of the policy, as of now.
+func (policy Policy) Query(now time.Time) jcapi.SystemQuery {
+	q := jcapi.SystemQuery{
+		Active:            jcapi.OnlyInactive,
+		LastContactBefore: now.AddDate(0, 0, -policy.InactiveDays),
+	}
+
+	if len(policy.OS) > 0 {
+		names := make([]string, len(policy.OS))
+		for idx, os := range policy.OS {
+			names[idx] = regexp.QuoteMeta(os)
+		}
+
+		q.OSPattern = "^(?:" + strings.Join(names, "|") + ")$"
+	}
+
+	return q
+}
+
+// excludes returns why the policy leaves out a system its query selected, "" when it doesn't.
+func (policy Policy) excludes(system jcapi.JCSystem) string {
+	for _, os := range policy.ExcludeOS {
+		if system.Os == os {
+			return fmt.Sprintf("the OS '%s' is excluded", os)
+		}
+	}
+
+	for _, tag := range system.Tags {
+		for _, name := range policy.ExcludeTags {
+			if tag.Name == name {
+				return fmt.Sprintf("the tag '%s' is excluded", name)
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package reaper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy(strings.NewReader(`{"inactiveDays": 60, "os": ["Ubuntu", "Mac OS X"], "excludeTags": ["servers"], "graceDays": 14, "maxDeletions": 10}`))
+	if err != nil {
+		t.Fatalf("LoadPolicy() failed, err='%s'", err)
+	}
+
+	if policy.InactiveDays != 60 || policy.GraceDays != 14 || policy.MaxDeletions != 10 || policy.quarantineTag() != DefaultQuarantineTag {
+		t.Fatalf("Unexpected policy: %+v", policy)
+	}
+
+	for _, data := range []string{
+		`{"inactiveDays": 0}`,
+		`{"inactiveDays": 30, "graceDays": -1}`,
+		`{"inactiveDays": 30, "excludeTags": ["reaper-quarantine"]}`,
+		`{"inactiveDays": 30, "inactivedays": 1, "maxDeletes": 5}`,
+	} {
+		if _, err := LoadPolicy(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected an error loading %s", data)
+		}
+	}
+}
+
+func TestPolicyQuery(t *testing.T) {
+	now := time.Date(2016, 5, 4, 0, 0, 0, 0, time.UTC)
+
+	q := Policy{InactiveDays: 3, OS: []string{"Ubuntu", "Mac OS X (10.11)"}}.Query(now)
+
+	if q.Active != jcapi.OnlyInactive || !q.LastContactBefore.Equal(time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected query: %+v", q)
+	}
+
+	if q.OSPattern != `^(?:Ubuntu|Mac OS X \(10\.11\))$` {
+		t.Fatalf("Unexpected OS pattern: %s", q.OSPattern)
+	}
+}
+
+func TestDirArchiveIds(t *testing.T) {
+	archive, err := NewDirArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirArchive() failed, err='%s'", err)
+	}
+
+	for _, id := range []string{"", "../etc", "a/b", ".hidden"} {
+		if err := archive.Save(Snapshot{System: jcapi.JCSystem{Id: id}}); err == nil {
+			t.Errorf("Expected an error saving a snapshot of system '%s'", id)
+		}
+	}
+
+	if _, found, err := archive.Load("missing"); found || err != nil {
+		t.Fatalf("Unexpected snapshot of a missing system, found=%t, err='%v'", found, err)
+	}
+}
@@ -0,0 +1,413 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+// Action is what a run does with a system
+type Action string
+
+const (
+	ActionQuarantine Action = "quarantine" // save the system to the archive and put it in the quarantine tag
+	ActionWait       Action = "wait"       // in quarantine for less than the grace period
+	ActionDelete     Action = "delete"     // in quarantine for the grace period, delete it
+	ActionHold       Action = "hold"       // due for deletion, but the run already deleted the most systems it may
+	ActionRelease    Action = "release"    // in quarantine, but no longer selected: take it out of the tag
+	ActionExclude    Action = "exclude"    // inactive, but left out by the policy
+)
+
+// Status is how far the action on a system got
+type Status string
+
+const (
+	StatusPlanned Status = "planned" // not applied, as in a dry run
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped" // not applied, as the run was cancelled or the deletion held
+)
+
+// Result is the line of the audit report about one system
+type Result struct {
+	Time          time.Time       `json:"time"` // when the action was applied, or planned
+	SystemId      string          `json:"systemId"`
+	Hostname      string          `json:"hostname,omitempty"`
+	Os            string          `json:"os,omitempty"`
+	LastContact   jcapi.Timestamp `json:"lastContact,omitzero"`
+	QuarantinedAt time.Time       `json:"quarantinedAt,omitzero"`
+	Action        Action          `json:"action"`
+	Status        Status          `json:"status"`
+	Reason        string          `json:"reason,omitempty"`
+	Error         string          `json:"error,omitempty"`
+
+	system   jcapi.JCSystem
+	snapshot Snapshot // the archived snapshot of a system in quarantine
+}
+
+// Report is the audit report of a run
+type Report struct {
+	Time    time.Time // when the run started
+	DryRun  bool
+	Policy  Policy
+	Results []Result
+}
+
+// Summary counts the systems of a report by outcome
+type Summary struct {
+	Systems     int  `json:"systems"`
+	Quarantined int  `json:"quarantined"`
+	Waiting     int  `json:"waiting"`
+	Deleted     int  `json:"deleted"`
+	Held        int  `json:"held"`
+	Released    int  `json:"released"`
+	Excluded    int  `json:"excluded"`
+	Failed      int  `json:"failed"`
+	Skipped     int  `json:"skipped"`
+	DryRun      bool `json:"dryRun"`
+}
+
+//
+// Reaper applies a policy to the systems of an account, keeping the snapshots of the
+// systems it quarantines in Archive. With DryRun set, it only reports what it would do.
+//
+type Reaper struct {
+	Policy  Policy
+	Archive Archive
+	DryRun  bool
+}
+
+//
+// Run selects the systems of the policy, quarantines the new ones, deletes those that
+// have been in quarantine for the grace period, and releases the quarantined systems the
+// policy no longer selects, such as those that made contact again. The report lists
+// every system the run considered, the oldest contact first.
+//
+// The error is only set when the run could not start; the failures of single systems
+// are in the report, and don't stop the others.
+//
+func (reaper Reaper) Run(ctx context.Context, jc jcapi.JCAPI) (*Report, error) {
+	if err := reaper.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	if reaper.Archive == nil {
+		return nil, fmt.Errorf("ERROR: The reaper needs an archive to save the systems it quarantines")
+	}
+
+	report := &Report{Time: time.Now(), DryRun: reaper.DryRun, Policy: reaper.Policy}
+
+	tags, err := jc.GetAllTagsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not get tags, err='%w'", err)
+	}
+
+	systems, err := jc.FindSystemsContext(ctx, reaper.Policy.Query(report.Time), false)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not search inactive systems, err='%w'", err)
+	}
+
+	quarantine := jcapi.JCTag{Name: reaper.Policy.quarantineTag()}
+
+	for _, tag := range tags {
+		if tag.Name == quarantine.Name {
+			quarantine = tag
+		}
+	}
+
+	if err := reaper.plan(report, quarantine, tags, systems); err != nil {
+		return nil, err
+	}
+
+	if !reaper.DryRun {
+		reaper.apply(ctx, jc, report, quarantine)
+	}
+
+	return report, nil
+}
+
+func newResult(report *Report, system jcapi.JCSystem) Result {
+	return Result{
+		Time:        report.Time,
+		SystemId:    system.Id,
+		Hostname:    system.Hostname,
+		Os:          system.Os,
+		LastContact: system.LastContact,
+		Status:      StatusPlanned,
+		system:      system,
+	}
+}
+
+func (reaper Reaper) plan(report *Report, quarantine jcapi.JCTag, tags []jcapi.JCTag, systems []jcapi.JCSystem) error {
+	inQuarantine := make(map[string]bool)
+	for _, id := range quarantine.Systems {
+		inQuarantine[id] = true
+	}
+
+	// The systems inactive the longest are deleted first, should the run hit MaxDeletions
+	sort.SliceStable(systems, func(i, j int) bool {
+		return systems[i].LastContact.Time().Before(systems[j].LastContact.Time())
+	})
+
+	considered := make(map[string]bool)
+	deletions := 0
+
+	for _, system := range systems {
+		system.AddJCTagsToSystem(tags)
+		considered[system.Id] = true
+
+		result := newResult(report, system)
+
+		snapshot, found, err := reaper.Archive.Load(system.Id)
+		if err != nil {
+			return err
+		}
+
+		if found && snapshot.inQuarantine() {
+			result.snapshot = snapshot
+			result.QuarantinedAt = snapshot.QuarantinedAt
+		}
+
+		if reason := reaper.Policy.excludes(system); reason != "" {
+			result.Action, result.Reason = ActionExclude, reason
+			if inQuarantine[system.Id] {
+				result.Action = ActionRelease
+			}
+
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		switch {
+		case !inQuarantine[system.Id] || result.QuarantinedAt.IsZero():
+			// Also quarantines again the systems taken out of the tag by hand, and
+			// those in the tag without a snapshot, restarting their grace period
+			result.Action = ActionQuarantine
+		case report.Time.Before(result.QuarantinedAt.AddDate(0, 0, reaper.Policy.GraceDays)):
+			result.Action = ActionWait
+		case reaper.Policy.MaxDeletions > 0 && deletions >= reaper.Policy.MaxDeletions:
+			result.Action = ActionHold
+			result.Reason = fmt.Sprintf("the policy deletes at most %d systems per run", reaper.Policy.MaxDeletions)
+		default:
+			result.Action = ActionDelete
+			deletions++
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	for _, id := range quarantine.Systems {
+		if considered[id] {
+			continue
+		}
+
+		considered[id] = true
+
+		snapshot, found, err := reaper.Archive.Load(id)
+		if err != nil {
+			return err
+		}
+
+		result := newResult(report, snapshot.System)
+		result.SystemId = id
+		result.Action = ActionRelease
+		result.Reason = "no longer selected by the policy"
+
+		if found && snapshot.inQuarantine() {
+			result.snapshot = snapshot
+			result.QuarantinedAt = snapshot.QuarantinedAt
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return nil
+}
+
+//
+// apply saves the snapshots and deletes the systems of the report, then updates the
+// quarantine tag once with the systems that went in and out of it, keeping the changes
+// made to the tag by others since it was read. A system is never deleted unless its
+// snapshot is saved right before.
+//
+func (reaper Reaper) apply(ctx context.Context, jc jcapi.JCAPI, report *Report, quarantine jcapi.JCTag) {
+	added := make(map[string]bool)
+	removed := make(map[string]bool)
+
+	var tagged []*Result // the results that depend on the update of the tag
+
+	for idx := range report.Results {
+		result := &report.Results[idx]
+
+		switch {
+		case result.Action == ActionWait || result.Action == ActionExclude:
+			result.Status = StatusDone
+			continue
+		case result.Action == ActionHold:
+			result.Status = StatusSkipped
+			continue
+		case ctx.Err() != nil:
+			result.Status = StatusSkipped
+			result.Error = ctx.Err().Error()
+			continue
+		}
+
+		result.Time = time.Now()
+
+		switch result.Action {
+		case ActionQuarantine:
+			snapshot := Snapshot{System: result.system, QuarantinedAt: result.Time}
+
+			if err := reaper.Archive.Save(snapshot); err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+				continue
+			}
+
+			result.snapshot = snapshot
+			result.QuarantinedAt = snapshot.QuarantinedAt
+			added[result.SystemId] = true
+		case ActionRelease:
+			removed[result.SystemId] = true
+		case ActionDelete:
+			if reaper.delete(ctx, jc, result) {
+				removed[result.SystemId] = true
+			}
+
+			continue
+		}
+
+		tagged = append(tagged, result)
+	}
+
+	var err error
+
+	switch {
+	case len(added) == 0 && len(removed) == 0:
+	case quarantine.Id == "":
+		quarantine.Systems = updateMembers(nil, added, removed)
+		_, err = jc.AddUpdateTagContext(ctx, jcapi.Insert, quarantine)
+	default:
+		_, err = jc.ModifyTagContext(ctx, quarantine.Name, func(tag *jcapi.JCTag) error {
+			tag.Systems = updateMembers(tag.Systems, added, removed)
+			return nil
+		})
+	}
+
+	for _, result := range tagged {
+		if err != nil {
+			result.Status = StatusFailed
+			result.Error = fmt.Sprintf("ERROR: Could not update tag '%s', err='%s'", quarantine.Name, err)
+			continue
+		}
+
+		if result.Action == ActionRelease && !result.snapshot.QuarantinedAt.IsZero() {
+			result.snapshot.ReleasedAt = time.Now()
+
+			if err := reaper.Archive.Save(result.snapshot); err != nil {
+				result.Error = err.Error()
+			}
+		}
+
+		result.Status = StatusDone
+	}
+}
+
+// updateMembers returns the systems of a tag without those removed, followed by those added
+func updateMembers(systems []string, added, removed map[string]bool) []string {
+	members := []string{}
+	seen := make(map[string]bool)
+
+	for _, id := range systems {
+		if !removed[id] && !seen[id] {
+			members = append(members, id)
+			seen[id] = true
+		}
+	}
+
+	var ids []string
+	for id := range added {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return append(members, ids...)
+}
+
+// delete refreshes the snapshot of the system of result, then deletes the system
+func (reaper Reaper) delete(ctx context.Context, jc jcapi.JCAPI, result *Result) bool {
+	snapshot := result.snapshot
+	snapshot.System = result.system
+
+	if err := reaper.Archive.Save(snapshot); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return false
+	}
+
+	if err := jc.DeleteSystemContext(ctx, result.system); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return false
+	}
+
+	result.Status = StatusDone
+
+	snapshot.DeletedAt = time.Now()
+
+	// The system is gone either way, but the report says the archive doesn't know
+	if err := reaper.Archive.Save(snapshot); err != nil {
+		result.Error = err.Error()
+	}
+
+	return true
+}
+
+// Summary counts the systems of the report by outcome.
+func (report *Report) Summary() Summary {
+	summary := Summary{Systems: len(report.Results), DryRun: report.DryRun}
+
+	for _, result := range report.Results {
+		switch {
+		case result.Status == StatusFailed:
+			summary.Failed++
+		case result.Status == StatusSkipped && result.Action != ActionHold:
+			summary.Skipped++
+		case result.Action == ActionQuarantine:
+			summary.Quarantined++
+		case result.Action == ActionWait:
+			summary.Waiting++
+		case result.Action == ActionDelete:
+			summary.Deleted++
+		case result.Action == ActionHold:
+			summary.Held++
+		case result.Action == ActionRelease:
+			summary.Released++
+		case result.Action == ActionExclude:
+			summary.Excluded++
+		}
+	}
+
+	return summary
+}
+
+// WriteJSON writes the result of every system of the report to w as JSON, one per line.
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	for _, result := range report.Results {
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("ERROR: Could not write report of system '%s', err='%w'", result.SystemId, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package reaper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheJumpCloud/jcapi"
+)
+
+//
+// accountServer searches its systems on /search/systems and lists its tags on /tags. It
+// also reads single tags by name on /tags/<name>. It records the tags written and the
+// systems deleted, failing the writes to the tags when failTags is set.
+//
+type accountServer struct {
+	sync.Mutex
+
+	systems  []map[string]interface{}
+	tags     []map[string]interface{}
+	failTags bool
+	onDelete func() // called on every deletion, as if done at the same time
+	writes   []string
+}
+
+func (s *accountServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == jcapi.SEARCH_SYSTEMS_PATH:
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalCount": len(s.systems), "results": s.systems})
+		return
+	case r.Method == http.MethodGet && r.URL.Path == jcapi.TAGS_PATH:
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalCount": len(s.tags), "results": s.tags})
+		return
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, jcapi.TAGS_PATH+"/"):
+		if tag := s.findTag("name", strings.TrimPrefix(r.URL.Path, jcapi.TAGS_PATH+"/")); tag != nil {
+			json.NewEncoder(w).Encode(tag)
+			return
+		}
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, jcapi.SYSTEMS_PATH+"/"):
+		s.writes = append(s.writes, "DELETE "+r.URL.Path)
+		if s.onDelete != nil {
+			s.onDelete()
+		}
+
+		w.Write([]byte("{}"))
+		return
+	case strings.HasPrefix(r.URL.Path, jcapi.TAGS_PATH):
+		var fields map[string]interface{}
+		json.Unmarshal(body, &fields)
+
+		s.writes = append(s.writes, r.Method+" "+r.URL.Path+" "+strings.Join(toStrings(fields["systems"]), ","))
+		if s.failTags {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		tag := s.findTag("_id", strings.TrimPrefix(r.URL.Path, jcapi.TAGS_PATH+"/"))
+		if tag == nil {
+			tag = map[string]interface{}{"_id": "new"}
+			s.tags = append(s.tags, tag)
+		}
+
+		for name, value := range fields {
+			tag[name] = value
+		}
+
+		json.NewEncoder(w).Encode(tag)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (s *accountServer) findTag(key, value string) map[string]interface{} {
+	for _, tag := range s.tags {
+		if tag[key] == value {
+			return tag
+		}
+	}
+
+	return nil
+}
+
+func toStrings(list interface{}) (strs []string) {
+	switch items := list.(type) {
+	case []string:
+		return items
+	case []interface{}:
+		for _, item := range items {
+			strs = append(strs, item.(string))
+		}
+	}
+
+	return
+}
+
+func inactiveSystem(id, hostname, os, lastContact string) map[string]interface{} {
+	return map[string]interface{}{"_id": id, "hostname": hostname, "os": os, "active": false, "lastContact": lastContact}
+}
+
+func newAccountServer(t *testing.T, quarantined ...string) (*accountServer, jcapi.JCAPI) {
+	server := &accountServer{
+		systems: []map[string]interface{}{
+			inactiveSystem("a", "web1", "Ubuntu", "2016-01-01T00:00:00Z"),
+			inactiveSystem("b", "db1", "Ubuntu", "2015-06-01T00:00:00Z"),
+			inactiveSystem("c", "mac1", "Mac OS X", "2015-07-01T00:00:00Z"),
+			inactiveSystem("d", "win1", "Windows", "2015-08-01T00:00:00Z"),
+			inactiveSystem("e", "keep1", "Ubuntu", "2016-02-01T00:00:00Z"),
+		},
+		tags: []map[string]interface{}{
+			{"_id": "t1", "name": "keep", "systems": []string{"e"}},
+		},
+	}
+
+	if quarantined != nil {
+		server.tags = append(server.tags, map[string]interface{}{"_id": "q1", "name": "quarantine", "systems": quarantined})
+	}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, jcapi.NewJCAPI("key", ts.URL)
+}
+
+func newArchive(t *testing.T, quarantinedDaysAgo map[string]int) *DirArchive {
+	archive, err := NewDirArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirArchive() failed, err='%s'", err)
+	}
+
+	for id, days := range quarantinedDaysAgo {
+		snapshot := Snapshot{System: jcapi.JCSystem{Id: id, Hostname: "host-" + id}, QuarantinedAt: time.Now().AddDate(0, 0, -days)}
+
+		if err := archive.Save(snapshot); err != nil {
+			t.Fatalf("Save() failed, err='%s'", err)
+		}
+	}
+
+	return archive
+}
+
+var testPolicy = Policy{InactiveDays: 30, GraceDays: 7, MaxDeletions: 1, ExcludeTags: []string{"keep"}, QuarantineTag: "quarantine"}
+
+func actionsOf(report *Report) string {
+	var actions []string
+	for _, result := range report.Results {
+		actions = append(actions, result.SystemId+":"+string(result.Action)+":"+string(result.Status))
+	}
+
+	return strings.Join(actions, " ")
+}
+
+func TestRun(t *testing.T) {
+	server, jc := newAccountServer(t, "b", "c", "d", "f")
+	archive := newArchive(t, map[string]int{"b": 10, "c": 10, "d": 1, "f": 20})
+
+	// Someone else puts a system in quarantine while the run is going on
+	server.onDelete = func() {
+		tag := server.findTag("_id", "q1")
+		tag["systems"] = append(toStrings(tag["systems"]), "z")
+	}
+
+	report, err := Reaper{Policy: testPolicy, Archive: archive}.Run(context.Background(), jc)
+	if err != nil {
+		t.Fatalf("Run() failed, err='%s'", err)
+	}
+
+	expected := "b:delete:done c:hold:skipped d:wait:done a:quarantine:done e:exclude:done f:release:done"
+	if s := actionsOf(report); s != expected {
+		t.Fatalf("Unexpected results: %s", s)
+	}
+
+	if s := strings.Join(server.writes, "\n"); s != "DELETE /systems/b\nPUT /tags/q1 c,d,z,a" {
+		t.Fatalf("Unexpected writes:\n%s", s)
+	}
+
+	summary := report.Summary()
+	if summary != (Summary{Systems: 6, Quarantined: 1, Waiting: 1, Deleted: 1, Held: 1, Released: 1, Excluded: 1}) {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+
+	// The snapshots record where each system is, with the latest copy of the deleted one
+	snapshot, found, _ := archive.Load("a")
+	if !found || !snapshot.inQuarantine() || snapshot.System.Hostname != "web1" {
+		t.Fatalf("Unexpected snapshot of the quarantined system: %+v", snapshot)
+	}
+
+	if snapshot, _, _ = archive.Load("b"); snapshot.DeletedAt.IsZero() || snapshot.System.Hostname != "db1" {
+		t.Fatalf("Unexpected snapshot of the deleted system: %+v", snapshot)
+	}
+
+	if snapshot, _, _ = archive.Load("f"); snapshot.ReleasedAt.IsZero() {
+		t.Fatalf("Unexpected snapshot of the released system: %+v", snapshot)
+	}
+
+	var buffer bytes.Buffer
+	if err := report.WriteJSON(&buffer); err != nil {
+		t.Fatalf("WriteJSON() failed, err='%s'", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 6 || !strings.Contains(lines[1], `"systemId":"c","hostname":"mac1","os":"Mac OS X","lastContact":"2015-07-01T00:00:00Z"`) ||
+		!strings.Contains(lines[1], `"action":"hold","status":"skipped","reason":"the policy deletes at most 1 systems per run"`) {
+		t.Fatalf("Unexpected report:\n%s", buffer.String())
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	server, jc := newAccountServer(t, "b")
+	archive := newArchive(t, map[string]int{"b": 10})
+
+	report, err := Reaper{Policy: testPolicy, Archive: archive, DryRun: true}.Run(context.Background(), jc)
+	if err != nil {
+		t.Fatalf("Run() failed, err='%s'", err)
+	}
+
+	if s := actionsOf(report); s != "b:delete:planned c:quarantine:planned d:quarantine:planned a:quarantine:planned e:exclude:planned" {
+		t.Fatalf("Unexpected results: %s", s)
+	}
+
+	if len(server.writes) != 0 {
+		t.Fatalf("A dry run wrote %v", server.writes)
+	}
+
+	if _, found, _ := archive.Load("a"); found {
+		t.Fatalf("A dry run saved a snapshot")
+	}
+}
+
+func TestRunCreatesQuarantineTag(t *testing.T) {
+	server, jc := newAccountServer(t)
+	archive := newArchive(t, nil)
+
+	policy := Policy{InactiveDays: 30, ExcludeOS: []string{"Windows"}}
+
+	report, err := Reaper{Policy: policy, Archive: archive}.Run(context.Background(), jc)
+	if err != nil {
+		t.Fatalf("Run() failed, err='%s'", err)
+	}
+
+	if s := actionsOf(report); s != "b:quarantine:done c:quarantine:done d:exclude:done a:quarantine:done e:quarantine:done" {
+		t.Fatalf("Unexpected results: %s", s)
+	}
+
+	if s := strings.Join(server.writes, "\n"); s != "POST /tags a,b,c,e" {
+		t.Fatalf("Unexpected writes:\n%s", s)
+	}
+}
+
+func TestRunTagFailure(t *testing.T) {
+	server, jc := newAccountServer(t, "b")
+	server.failTags = true
+
+	archive := newArchive(t, map[string]int{"b": 10})
+
+	policy := testPolicy
+	policy.OS = []string{"Ubuntu"}
+
+	report, err := Reaper{Policy: policy, Archive: archive}.Run(context.Background(), jc)
+	if err != nil {
+		t.Fatalf("Run() failed, err='%s'", err)
+	}
+
+	// The deletion doesn't depend on the tag, but a system is only quarantined once tagged
+	if s := actionsOf(report); s != "b:delete:done a:quarantine:failed e:exclude:done" {
+		t.Fatalf("Unexpected results: %s", s)
+	}
+
+	if !strings.Contains(report.Results[1].Error, "Could not update tag 'quarantine'") {
+		t.Fatalf("Unexpected error: %s", report.Results[1].Error)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	server, jc := newAccountServer(t, "b")
+	archive := newArchive(t, map[string]int{"b": 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := (Reaper{Policy: testPolicy, Archive: archive}).Run(ctx, jc); err == nil {
+		t.Fatalf("Expected a cancelled run to fail")
+	}
+
+	if len(server.writes) != 0 {
+		t.Fatalf("A cancelled run wrote %v", server.writes)
+	}
+}